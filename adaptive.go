@@ -0,0 +1,39 @@
+package expiringcache
+
+const defaultAdaptiveHits = 2
+
+// adaptDuration returns the duration to store cv for when it is written
+// again, based on how often it was fetched since it was last written.
+// It starts from the duration the key was stored for, before jitter and
+// rounding, so these don't add up over successive writes.
+func (p *Cache) adaptDuration(cv *CacheValue) int {
+	// keys that never expire are left that way
+	if cv.duration <= 0 {
+		return 0
+	}
+
+	threshold := int64(p.AdaptiveHits)
+	if threshold <= 0 {
+		threshold = defaultAdaptiveHits
+	}
+
+	d := int(cv.duration)
+	switch hits := cv.HitCount - cv.writeHits; {
+	case hits >= threshold:
+		d *= 2
+	case hits == 0:
+		d /= 2
+	}
+
+	if d < p.MinDuration {
+		d = p.MinDuration
+	}
+	if p.MaxDuration > 0 && d > p.MaxDuration {
+		d = p.MaxDuration
+	}
+	if d < 1 {
+		d = 1
+	}
+
+	return d
+}
@@ -0,0 +1,69 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTTL(t *testing.T) {
+	cache := Cache{Duration: 60, AdaptiveTTL: true, MinDuration: 20,
+		MaxDuration: 200}
+	cache.Init()
+
+	ttl := func() time.Duration {
+		d, _ := cache.TTL("a")
+		return d.Round(10 * time.Second)
+	}
+
+	cache.Put("a", 1)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Put("a", 2)
+	if ttl() != 120*time.Second {
+		t.Errorf("TTL of hot key is %v, expected 2m", ttl())
+	}
+
+	cache.Get("a")
+	cache.Get("a")
+	cache.Put("a", 3)
+	if ttl() != 200*time.Second {
+		t.Errorf("TTL of hot key is %v, expected MaxDuration", ttl())
+	}
+
+	cache.Get("a")
+	cache.Put("a", 4)
+	if ttl() != 200*time.Second {
+		t.Errorf("TTL of lukewarm key changed to %v", ttl())
+	}
+
+	cache.Put("a", 5)
+	cache.Put("a", 6)
+	cache.Put("a", 7)
+	if ttl() != 20*time.Second {
+		t.Errorf("TTL of cold key is %v, expected MinDuration", ttl())
+	}
+
+	// explicit durations are not adapted
+	cache.PutWithExpiry("a", 8, 30)
+	if ttl() != 30*time.Second {
+		t.Errorf("Explicit duration replaced with %v", ttl())
+	}
+}
+
+func TestAdaptiveTTLRounding(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Duration: 10, AdaptiveTTL: true, ExpiryGranularity: 7,
+		Clock: func() time.Time { return now }}
+	cache.Init()
+
+	// a lukewarm key keeps its duration, rounding up to the granularity
+	// must not make it grow with every write
+	for i := 0; i < 10; i++ {
+		cache.Put("a", i)
+		cache.Get("a")
+		now = now.Add(time.Second)
+	}
+	if d, _ := cache.TTL("a"); d > 16*time.Second {
+		t.Errorf("TTL of lukewarm key grew to %v", d)
+	}
+}
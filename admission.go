@@ -0,0 +1,65 @@
+package expiringcache
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Admitter decides whether a new key is allowed into a full cache. It is
+// consulted only when adding the key would require evicting others, so a
+// burst of one-off keys cannot flush the entire working set.
+type Admitter interface {
+	Admit(key string) bool
+}
+
+// ProbabilisticAdmitter admits a new key with the given probability
+// (between 0 and 1). If the cache has a RandSource, the decisions are drawn
+// from it so they can be reproduced; the admitter must then not be shared
+// between caches.
+type ProbabilisticAdmitter struct {
+	Probability float64
+
+	float64 func() float64 // the cache's source, called with its lock held
+}
+
+func (p *ProbabilisticAdmitter) Admit(key string) bool {
+	if p.float64 != nil {
+		return p.float64() < p.Probability
+	}
+	return rand.Float64() < p.Probability
+}
+
+// TokenBucketAdmitter admits new keys at a sustained rate of Rate keys per
+// second, allowing bursts of up to Burst keys.
+type TokenBucketAdmitter struct {
+	Rate  float64
+	Burst int
+
+	tokens float64
+	last   time.Time
+	sync.Mutex
+}
+
+func (p *TokenBucketAdmitter) Admit(key string) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	now := time.Now()
+	if p.last.IsZero() {
+		p.tokens = float64(p.Burst)
+	} else {
+		p.tokens += now.Sub(p.last).Seconds() * p.Rate
+		if p.tokens > float64(p.Burst) {
+			p.tokens = float64(p.Burst)
+		}
+	}
+	p.last = now
+
+	if p.tokens < 1 {
+		return false
+	}
+
+	p.tokens--
+	return true
+}
@@ -0,0 +1,60 @@
+package expiringcache
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestTokenBucketAdmission(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 2, NEvictions: 1,
+		Admitter: &TokenBucketAdmitter{Rate: 0, Burst: 1}}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+
+	// cache is full; only one new key may displace the working set
+	cache.Put("c", 3)
+	cache.Put("d", 4)
+
+	if !cache.Exists("c") {
+		t.Errorf("Key within burst was not admitted")
+	}
+
+	if cache.Exists("d") {
+		t.Errorf("Key beyond burst was admitted")
+	}
+
+	// updates to existing keys never need admission
+	cache.Put("c", 5)
+	if cache.Get("c").(int) != 5 {
+		t.Errorf("Update of existing key was rejected")
+	}
+}
+
+func TestProbabilisticAdmission(t *testing.T) {
+	admitted := func() string {
+		cache := Cache{Duration: 60, Max: 1, NEvictions: 1,
+			RandSource: rand.NewSource(1),
+			Admitter:   &ProbabilisticAdmitter{Probability: 0.5}}
+		cache.Init()
+
+		var keys []byte
+		for i := 0; i < 32; i++ {
+			key := strconv.Itoa(i)
+			cache.Put(key, i)
+			if cache.Exists(key) {
+				keys = append(keys, '1')
+			} else {
+				keys = append(keys, '0')
+			}
+		}
+		return string(keys)
+	}
+
+	// the same source admits the same keys
+	if a, b := admitted(), admitted(); a != b {
+		t.Errorf("Admitted %s, then %s", a, b)
+	}
+}
@@ -0,0 +1,116 @@
+package expiringcache
+
+import (
+	"container/list"
+)
+
+// arc implements the Adaptive Replacement Cache policy. Keys seen once are
+// in t1 and keys seen more than once in t2, both ordered from the most to
+// the least recently used. b1 and b2 remember the keys recently evicted
+// from each. A key coming back after eviction from t1 means t1 should have
+// been larger, so its target size grows, and the other way round for t2.
+type arc struct {
+	t1, t2, b1, b2 *list.List
+	elems          map[string]*list.Element
+	lists          map[string]*list.List
+	target         int // desired size of t1
+	capacity       int
+}
+
+func newARC(capacity int) *arc {
+	return &arc{t1: list.New(), t2: list.New(), b1: list.New(),
+		b2: list.New(), elems: make(map[string]*list.Element),
+		lists: make(map[string]*list.List), capacity: capacity}
+}
+
+func (p *arc) OnAdd(cv *CacheValue) {
+	switch p.lists[cv.Key] {
+	case p.b1:
+		p.target = min(p.target+max(p.b2.Len()/p.b1.Len(), 1), p.size())
+		p.drop(cv.Key)
+		p.push(p.t2, cv.Key, cv)
+	case p.b2:
+		p.target = max(p.target-max(p.b1.Len()/p.b2.Len(), 1), 0)
+		p.drop(cv.Key)
+		p.push(p.t2, cv.Key, cv)
+	default:
+		p.push(p.t1, cv.Key, cv)
+	}
+
+	p.trimGhosts()
+}
+
+func (p *arc) OnAccess(cv *CacheValue) {
+	if l := p.lists[cv.Key]; l == p.t1 || l == p.t2 {
+		p.drop(cv.Key)
+		p.push(p.t2, cv.Key, cv)
+	}
+}
+
+func (p *arc) OnRemove(cv *CacheValue, typ EventType) {
+	l := p.lists[cv.Key]
+	if l != p.t1 && l != p.t2 {
+		return
+	}
+
+	p.drop(cv.Key)
+
+	// only evicted keys are remembered, as only they could have been
+	// kept by a better choice
+	if typ == EventEvict {
+		if l == p.t1 {
+			p.push(p.b1, cv.Key, nil)
+		} else {
+			p.push(p.b2, cv.Key, nil)
+		}
+		p.trimGhosts()
+	}
+}
+
+func (p *arc) Victim() *CacheValue {
+	l := p.t2
+	if p.t1.Len() > 0 && (p.t1.Len() > p.target || p.t2.Len() == 0) {
+		l = p.t1
+	}
+
+	if e := l.Back(); e != nil {
+		return e.Value.(*CacheValue)
+	}
+	return nil
+}
+
+// size is the number of keys the cache holds when full, or currently holds
+// if it has no limit
+func (p *arc) size() int {
+	return max(p.capacity, p.t1.Len()+p.t2.Len(), 1)
+}
+
+// trimGhosts bounds the remembered keys to the size of the cache
+func (p *arc) trimGhosts() {
+	c := p.size()
+	for p.b1.Len() > 0 && p.t1.Len()+p.b1.Len() > c {
+		p.drop(p.b1.Back().Value.(string))
+	}
+	for p.b2.Len() > 0 && p.t1.Len()+p.t2.Len()+p.b1.Len()+p.b2.Len() > 2*c {
+		p.drop(p.b2.Back().Value.(string))
+	}
+}
+
+// push adds key to the front of l, storing cv for resident keys and the
+// key itself for remembered ones
+func (p *arc) push(l *list.List, key string, cv *CacheValue) {
+	var v interface{} = key
+	if cv != nil {
+		v = cv
+	}
+	p.elems[key] = l.PushFront(v)
+	p.lists[key] = l
+}
+
+func (p *arc) drop(key string) {
+	if l, ok := p.lists[key]; ok {
+		l.Remove(p.elems[key])
+		delete(p.elems, key)
+		delete(p.lists, key)
+	}
+}
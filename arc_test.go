@@ -0,0 +1,37 @@
+package expiringcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestARC(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 4, NEvictions: 1, Policy: PolicyARC}
+	cache.Init()
+
+	cache.Put("h1", 1)
+	cache.Put("h2", 2)
+	cache.Get("h1")
+	cache.Get("h2")
+
+	// a scan of keys used once doesn't push out the frequently used ones
+	for i := 0; i < 10; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+
+	if !cache.Exists("h1") || !cache.Exists("h2") || cache.Count() != 4 {
+		t.Errorf("Scan evicted frequently used keys")
+	}
+
+	// a recently evicted key coming back grows the share of keys used once
+	arc := cache.policy.(*arc)
+	cache.Put("7", 7)
+	if arc.target == 0 || arc.lists["7"] != arc.t2 {
+		t.Errorf("Returning key did not adapt the target")
+	}
+
+	cache.Del("h1")
+	if _, ok := arc.lists["h1"]; ok {
+		t.Errorf("Deleted key still tracked")
+	}
+}
@@ -0,0 +1,88 @@
+package expiringcache
+
+import "context"
+
+// GetMany returns the values of the keys found in the cache, taking the
+// lock once for all of them
+func (p *Cache) GetMany(keys []string) map[string]interface{} {
+	values, _ := p.getMany(keys)
+	return values
+}
+
+// getMany returns the values of the keys found and the keys missing
+func (p *Cache) getMany(keys []string) (map[string]interface{}, []string) {
+	values := make(map[string]interface{}, len(keys))
+	var misses []string
+
+	p.Lock()
+	defer p.Unlock()
+
+	for _, key := range keys {
+		if v, ok := p.get(key); ok {
+			values[key] = v
+		} else {
+			misses = append(misses, key)
+		}
+	}
+
+	return values, misses
+}
+
+// GetManyCtx is like GetMany but loads the missing keys with BatchLoader,
+// or Loader if that isn't set, and stores them as with Put. Keys that
+// aren't found by either are left out of the result. With Loader,
+// concurrent misses of a key share one call as in GetCtx. If loading
+// fails, the values found so far are returned with the error.
+func (p *Cache) GetManyCtx(ctx context.Context,
+	keys []string) (map[string]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	values, misses := p.getMany(keys)
+	if len(misses) == 0 {
+		return values, nil
+	}
+
+	loaded := make(map[string]interface{}, len(misses))
+	switch {
+	case p.BatchLoader != nil:
+		lctx, end := p.trace(ctx, "load", "")
+		m, err := p.BatchLoader(lctx, misses)
+		end(false, err)
+		if err != nil {
+			return values, err
+		}
+
+		// ignore keys that weren't asked for
+		for _, key := range misses {
+			if v, ok := m[key]; ok {
+				loaded[key] = v
+			}
+		}
+
+	case p.Loader != nil:
+		for _, key := range misses {
+			lctx, end := p.trace(ctx, "load", key)
+			v, shared, err := p.loadShared(lctx, key)
+			end(false, err)
+			if err != nil {
+				return values, err
+			}
+			if shared {
+				values[key] = v
+			} else {
+				loaded[key] = v
+			}
+		}
+	}
+
+	for key, v := range loaded {
+		if err := p.putLoaded(key, v); err != nil {
+			return values, err
+		}
+		values[key] = v
+	}
+
+	return values, nil
+}
@@ -0,0 +1,68 @@
+package expiringcache
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGetManyCtx(t *testing.T) {
+	var batches [][]string
+	cache := Cache{Duration: 60,
+		BatchLoader: func(ctx context.Context,
+			keys []string) (map[string]interface{}, error) {
+			batches = append(batches, keys)
+			return map[string]interface{}{"b": 2, "c": 3, "x": 9}, nil
+		}}
+	cache.Init()
+	cache.Put("a", 1)
+
+	values, err := cache.GetManyCtx(context.Background(),
+		[]string{"a", "b", "c", "d"})
+	if err != nil || !reflect.DeepEqual(values,
+		map[string]interface{}{"a": 1, "b": 2, "c": 3}) {
+		t.Errorf("GetManyCtx returned %v, %v", values, err)
+	}
+
+	if len(batches) != 1 || !reflect.DeepEqual(batches[0],
+		[]string{"b", "c", "d"}) {
+		t.Errorf("BatchLoader called with %v", batches)
+	}
+	if cache.Get("b") != 2 || cache.Exists("x") {
+		t.Errorf("Loaded values not stored as expected")
+	}
+
+	values = cache.GetMany([]string{"a", "b", "d"})
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"a", "b"}) {
+		t.Errorf("GetMany returned %v", values)
+	}
+}
+
+func TestGetManyCtxLoader(t *testing.T) {
+	fail := errors.New("down")
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			if key == "bad" {
+				return nil, fail
+			}
+			return key + "!", nil
+		}}
+	cache.Init()
+
+	values, err := cache.GetManyCtx(context.Background(), []string{"a", "b"})
+	if err != nil || values["a"] != "a!" || values["b"] != "b!" {
+		t.Errorf("GetManyCtx returned %v, %v", values, err)
+	}
+
+	if _, err := cache.GetManyCtx(context.Background(),
+		[]string{"a", "bad"}); err != fail {
+		t.Errorf("GetManyCtx with failing Loader returned %v", err)
+	}
+}
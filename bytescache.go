@@ -0,0 +1,258 @@
+package expiringcache
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+)
+
+const (
+	defaultArenaSize = 64 << 20
+
+	// entry header: hash(8) expireAt(8) keyLen(2) flags(1) deleted(1) valLen(4)
+	headerSize    = 24
+	flagsOffset   = 18
+	deletedOffset = 19
+	valLenOffset  = 20
+	maxKeyLen     = 1<<16 - 1
+
+	flagCompressed = 1 << 0
+)
+
+// BytesCache is a cache specialized for []byte values. Entries are copied
+// into a single pre-allocated ring buffer and indexed by key hash, so the
+// garbage collector has no per-entry pointers to scan no matter how many
+// entries are stored. When the buffer is full the oldest entries are
+// overwritten.
+type BytesCache struct {
+	Duration  int // Number of seconds to keep key in cache, 0 for never
+	ArenaSize int // size in bytes of the ring buffer. Defaults to 64MB
+
+	// Compressor, if set, compresses values of at least CompressThreshold
+	// bytes, trading CPU for memory.
+	Compressor        Compressor
+	CompressThreshold int
+
+	// Largest value accepted by Put, 0 for no limit (other than ArenaSize)
+	MaxValueBytes int
+	// What Put does with values larger than MaxValueBytes
+	OversizePolicy OversizePolicy
+
+	arena   []byte
+	index   map[uint64]uint32 // key hash -> offset of entry in arena
+	head    int               // offset at which the next entry is written
+	tail    int               // offset of the oldest entry
+	wrapAt  int               // end of the entries before head wrapped to 0
+	wrapped bool
+	records int // entries in arena, including deleted ones
+	sync.Mutex
+}
+
+func (p *BytesCache) Init() {
+	n := p.ArenaSize
+	if n == 0 {
+		n = defaultArenaSize
+	}
+
+	p.arena = make([]byte, n)
+	p.index = make(map[uint64]uint32)
+}
+
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func (p *BytesCache) Put(key string, value []byte) error {
+	return p.PutWithExpiry(key, value, p.Duration)
+}
+
+func (p *BytesCache) PutWithExpiry(key string, value []byte, duration int) error {
+	v, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+	value = v.([]byte)
+
+	var flags byte
+	if p.Compressor != nil && len(value) >= p.CompressThreshold {
+		c, err := p.Compressor.Compress(value)
+		if err != nil {
+			return err
+		}
+
+		// keep the original when compression doesn't help
+		if len(c) < len(value) {
+			value = c
+			flags |= flagCompressed
+		}
+	}
+
+	size := headerSize + len(key) + len(value)
+	if len(key) > maxKeyLen || size > len(p.arena) {
+		return ErrTooLarge
+	}
+
+	h := hashKey(key)
+
+	p.Lock()
+	defer p.Unlock()
+
+	if off, ok := p.index[h]; ok {
+		p.arena[int(off)+deletedOffset] = 1
+		delete(p.index, h)
+	}
+
+	off := p.alloc(size)
+	b := p.arena[off : off+size]
+	binary.LittleEndian.PutUint64(b[0:], h)
+	var expireAt int64
+	if duration > 0 {
+		expireAt = now() + int64(duration)
+	}
+	binary.LittleEndian.PutUint64(b[8:], uint64(expireAt))
+	binary.LittleEndian.PutUint16(b[16:], uint16(len(key)))
+	b[flagsOffset] = flags
+	b[deletedOffset] = 0
+	binary.LittleEndian.PutUint32(b[valLenOffset:], uint32(len(value)))
+	copy(b[headerSize:], key)
+	copy(b[headerSize+len(key):], value)
+
+	p.index[h] = uint32(off)
+	return nil
+}
+
+// alloc makes room for size bytes, evicting the oldest entries as needed,
+// and returns the offset of the space.
+func (p *BytesCache) alloc(size int) int {
+	for {
+		if p.records == 0 {
+			p.head, p.tail, p.wrapped = 0, 0, false
+		}
+
+		if !p.wrapped {
+			if p.head+size <= len(p.arena) {
+				break
+			}
+
+			p.wrapAt = p.head
+			p.head = 0
+			p.wrapped = true
+			continue
+		}
+
+		if p.head+size <= p.tail {
+			break
+		}
+
+		p.evictOldest()
+	}
+
+	off := p.head
+	p.head += size
+	p.records++
+	return off
+}
+
+func (p *BytesCache) evictOldest() {
+	off := p.tail
+	h, _, _, size := p.header(off)
+	if p.arena[off+deletedOffset] == 0 {
+		delete(p.index, h)
+	}
+
+	p.tail += size
+	p.records--
+	if p.wrapped && p.tail == p.wrapAt {
+		p.tail = 0
+		p.wrapped = false
+	}
+}
+
+// header returns the hash, expiry and key length of the entry at off, and
+// its total size
+func (p *BytesCache) header(off int) (uint64, int64, int, int) {
+	b := p.arena[off:]
+	h := binary.LittleEndian.Uint64(b[0:])
+	expireAt := int64(binary.LittleEndian.Uint64(b[8:]))
+	keyLen := int(binary.LittleEndian.Uint16(b[16:]))
+	valLen := int(binary.LittleEndian.Uint32(b[valLenOffset:]))
+	return h, expireAt, keyLen, headerSize + keyLen + valLen
+}
+
+// find returns the offset of key's entry, must be called with lock held
+func (p *BytesCache) find(key string) (int, bool) {
+	h := hashKey(key)
+	off, ok := p.index[h]
+	if !ok {
+		return 0, false
+	}
+
+	_, expireAt, keyLen, _ := p.header(int(off))
+	k := p.arena[int(off)+headerSize : int(off)+headerSize+keyLen]
+	if string(k) != key {
+		// hash collision with another key
+		return 0, false
+	}
+
+	if expireAt != 0 && expireAt <= now() {
+		p.arena[int(off)+deletedOffset] = 1
+		delete(p.index, h)
+		return 0, false
+	}
+
+	return int(off), true
+}
+
+// Get returns a copy of the value stored for key, or nil if there is none,
+// it has expired or it cannot be decompressed.
+func (p *BytesCache) Get(key string) []byte {
+	p.Lock()
+
+	off, ok := p.find(key)
+	if !ok {
+		p.Unlock()
+		return nil
+	}
+
+	_, _, keyLen, size := p.header(off)
+	flags := p.arena[off+flagsOffset]
+	v := make([]byte, size-headerSize-keyLen)
+	copy(v, p.arena[off+headerSize+keyLen:off+size])
+	p.Unlock()
+
+	if flags&flagCompressed == 0 {
+		return v
+	}
+
+	v, err := p.Compressor.Decompress(v)
+	if err != nil {
+		return nil
+	}
+
+	return v
+}
+
+func (p *BytesCache) Del(key string) {
+	p.Lock()
+	if off, ok := p.find(key); ok {
+		p.arena[off+deletedOffset] = 1
+		delete(p.index, hashKey(key))
+	}
+	p.Unlock()
+}
+
+func (p *BytesCache) Exists(key string) bool {
+	p.Lock()
+	_, ok := p.find(key)
+	p.Unlock()
+	return ok
+}
+
+func (p *BytesCache) Count() int {
+	p.Lock()
+	count := len(p.index)
+	p.Unlock()
+	return count
+}
@@ -0,0 +1,91 @@
+package expiringcache
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBytesCache(t *testing.T) {
+	cache := BytesCache{Duration: 60, ArenaSize: 1024}
+	cache.Init()
+
+	cache.Put("a", []byte("1"))
+	cache.Put("a", []byte("2"))
+	if !bytes.Equal(cache.Get("a"), []byte("2")) {
+		t.Errorf("Get did not fetch correct value")
+	}
+
+	cache.Del("a")
+	if cache.Exists("a") || cache.Count() != 0 {
+		t.Errorf("Del did not remove key")
+	}
+
+	cache.PutWithExpiry("b", []byte("2"), 1)
+	cache.PutWithExpiry("forever", []byte("3"), 0)
+	time.Sleep(1100 * time.Millisecond)
+	if cache.Get("b") != nil {
+		t.Errorf("Get returned an expired value")
+	}
+
+	if !bytes.Equal(cache.Get("forever"), []byte("3")) {
+		t.Errorf("Key without expiry was expired")
+	}
+
+	if cache.Put("c", make([]byte, 1024)) != ErrTooLarge {
+		t.Errorf("Value larger than arena was accepted")
+	}
+}
+
+func TestBytesCacheWrap(t *testing.T) {
+	cache := BytesCache{Duration: 60, ArenaSize: 1000}
+	cache.Init()
+
+	value := make([]byte, 70)
+	for i := 0; i < 100; i++ {
+		key := strconv.Itoa(i)
+		value[0] = byte(i)
+		if err := cache.Put(key, value); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+
+		v := cache.Get(key)
+		if v == nil || v[0] != byte(i) {
+			t.Fatalf("Get did not fetch the latest value")
+		}
+	}
+
+	// each entry takes 24+2+70 bytes so only the newest 10 fit
+	if cache.Count() != 10 {
+		t.Errorf("Count is %d after wrapping, expected 10", cache.Count())
+	}
+
+	if cache.Exists("89") || !cache.Exists("90") {
+		t.Errorf("Oldest entries were not the ones overwritten")
+	}
+}
+
+func FuzzBytesCache(f *testing.F) {
+	f.Add("a", []byte("1"))
+	f.Add("", []byte{})
+
+	f.Fuzz(func(t *testing.T, key string, value []byte) {
+		cache := BytesCache{Duration: 60, ArenaSize: 256,
+			Compressor: &GzipCompressor{}, CompressThreshold: 16}
+		cache.Init()
+
+		err := cache.Put(key, value)
+		if err == ErrTooLarge {
+			return
+		}
+
+		if err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+
+		if v := cache.Get(key); !bytes.Equal(v, value) {
+			t.Errorf("Get returned %q, expected %q", v, value)
+		}
+	})
+}
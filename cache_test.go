@@ -1,6 +1,11 @@
 package expiringcache
 
 import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -34,3 +39,400 @@ func TestExpiringCache(t *testing.T) {
 		t.Errorf("Get did not fetch correct value")
 	}
 }
+
+func TestPriorityEviction(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 2, NEvictions: 1, NSamples: 50}
+	cache.Init()
+
+	cache.PutWithPriority("hi", 1, 1, 10)
+	cache.PutWithPriority("lo", 2, 60, 0)
+	cache.Put("c", 3)
+
+	if !cache.Exists("hi") {
+		t.Errorf("High priority key evicted before low priority key")
+	}
+
+	if cache.Exists("lo") {
+		t.Errorf("Low priority key not evicted on reaching max capacity")
+	}
+}
+
+func TestEntryMetadata(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.Get("a")
+	cache.Get("a")
+
+	e, ok := cache.GetEntry("a")
+	if !ok {
+		t.Fatalf("GetEntry failed to find entry")
+	}
+
+	if e.HitCount != 2 {
+		t.Errorf("HitCount is %d, expected 2", e.HitCount)
+	}
+
+	if e.CreatedAt == 0 || e.LastAccessedAt < e.CreatedAt {
+		t.Errorf("Access timestamps not recorded")
+	}
+
+	if _, ok := cache.GetEntry("b"); ok {
+		t.Errorf("GetEntry found a missing key")
+	}
+}
+
+func TestTTLJitter(t *testing.T) {
+	cache := Cache{Duration: 100, TTLJitter: 0.5}
+	cache.Init()
+
+	ts := time.Now().UTC().Unix()
+	seen := make(map[int64]bool)
+	for i := 0; i < 50; i++ {
+		key := strconv.Itoa(i)
+		cache.Put(key, i)
+
+		e, _ := cache.GetEntry(key)
+		if e.ExpireAt < ts+50 || e.ExpireAt > ts+151 {
+			t.Errorf("Jittered expiry %d outside of bounds", e.ExpireAt-ts)
+		}
+		seen[e.ExpireAt] = true
+	}
+
+	if len(seen) < 2 {
+		t.Errorf("Expiry times were not jittered")
+	}
+}
+
+func TestRandSource(t *testing.T) {
+	evicted := func() []string {
+		cache := Cache{Duration: 60, Max: 10, NEvictions: 1,
+			RandSource: rand.NewSource(1)}
+		cache.Init()
+
+		var keys []string
+		events, cancel := cache.Subscribe()
+		defer cancel()
+
+		for i := 0; i < 20; i++ {
+			cache.Put(strconv.Itoa(i), i)
+		}
+
+		for len(events) > 0 {
+			if e := <-events; e.Type == EventEvict {
+				keys = append(keys, e.Key)
+			}
+		}
+		return keys
+	}
+
+	a, b := evicted(), evicted()
+	if len(a) == 0 || strings.Join(a, ",") != strings.Join(b, ",") {
+		t.Errorf("Evictions not reproducible: %v and %v", a, b)
+	}
+}
+
+func TestEvictSoonest(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 3, NEvictions: 1, EvictSoonest: true}
+	cache.Init()
+
+	cache.PutWithExpiry("a", 1, 30)
+	cache.PutWithExpiry("b", 2, 10)
+	cache.PutWithExpiry("c", 3, 20)
+	cache.Put("d", 4)
+
+	if cache.Exists("b") || cache.Count() != 3 {
+		t.Errorf("Soonest expiring key not evicted")
+	}
+}
+
+func TestEvictExpired(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	for i := 0; i < 10; i++ {
+		cache.PutWithDeadline(strconv.Itoa(i), i, time.Now().Add(-time.Second))
+	}
+	cache.Put("live", 1)
+
+	if n := cache.EvictExpired(4, 0); n != 4 {
+		t.Errorf("Removed %d keys, expected budget of 4", n)
+	}
+
+	if n := cache.EvictExpired(0, time.Second); n != 6 {
+		t.Errorf("Removed %d keys, expected remaining 6", n)
+	}
+
+	if !cache.Exists("live") || cache.Count() != 1 {
+		t.Errorf("Unexpired key removed")
+	}
+}
+
+func TestEvictExpiredTimeBudget(t *testing.T) {
+	// removing each key is slow, so the budget runs out part way
+	cache := Cache{Duration: 60,
+		Disposer: func(value interface{}) { time.Sleep(time.Millisecond) }}
+	cache.Init()
+
+	// the expired keys start after the first, at an odd position
+	cache.Put("a", 1)
+	for i := 0; i < 200; i++ {
+		cache.PutWithDeadline(fmt.Sprintf("b%03d", i), i,
+			time.Now().Add(-time.Second))
+	}
+
+	if n := cache.EvictExpired(0, 10*time.Millisecond); n == 0 || n >= 200 {
+		t.Errorf("Removed %d keys, expected the time budget to stop it", n)
+	}
+}
+
+func TestSweepBatches(t *testing.T) {
+	cache := Cache{Duration: 60, SweepBatchSize: 3}
+	cache.Init()
+
+	for i := 0; i < 10; i++ {
+		cache.PutWithDeadline(strconv.Itoa(i), i, time.Now().Add(-time.Second))
+		cache.Put("live"+strconv.Itoa(i), i)
+	}
+
+	if n := cache.sweep(); n != 10 {
+		t.Errorf("Sweep removed %d keys, expected 10", n)
+	}
+
+	if cache.Count() != 10 {
+		t.Errorf("Sweep removed unexpired keys")
+	}
+}
+
+func TestActiveExpiry(t *testing.T) {
+	cache := Cache{Duration: 60, ActiveExpirySamples: 20}
+	cache.Init()
+
+	for i := 0; i < 100; i++ {
+		cache.PutWithDeadline(strconv.Itoa(i), i, time.Now().Add(-time.Second))
+	}
+	cache.Put("live", 1)
+
+	// mostly expired keys, so sampling keeps going
+	if n := cache.expireSampled(); n <= 20 {
+		t.Errorf("Sampling stopped after %d keys despite many expired", n)
+	}
+
+	if !cache.Exists("live") {
+		t.Errorf("Unexpired key removed")
+	}
+}
+
+func TestTTLFunc(t *testing.T) {
+	cache := Cache{Duration: 60,
+		TTLFunc: func(key string, value interface{}) time.Duration {
+			if d, ok := value.(time.Duration); ok {
+				return d
+			}
+			return 0
+		}}
+	cache.Init()
+
+	ts := time.Now().UTC().Unix()
+	cache.Put("a", 10*time.Second)
+	cache.Put("b", "x")
+
+	if e, _ := cache.GetEntry("a"); e.ExpireAt-ts < 10 || e.ExpireAt-ts > 11 {
+		t.Errorf("TTLFunc duration not used")
+	}
+
+	if e, _ := cache.GetEntry("b"); e.ExpireAt-ts < 60 || e.ExpireAt-ts > 61 {
+		t.Errorf("Duration not used as fallback")
+	}
+}
+
+func TestSwapAndDel(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	if old, ok, _ := cache.Swap("a", 1); ok || old != nil {
+		t.Errorf("Swap of new key returned %v, %v", old, ok)
+	}
+
+	if old, ok, _ := cache.Swap("a", 2); !ok || old != 1 {
+		t.Errorf("Swap returned %v, %v, expected 1, true", old, ok)
+	}
+
+	if old, ok := cache.DelValue("a"); !ok || old != 2 {
+		t.Errorf("DelValue returned %v, %v, expected 2, true", old, ok)
+	}
+
+	if _, ok := cache.DelValue("a"); ok {
+		t.Errorf("DelValue found missing key")
+	}
+}
+
+func TestPop(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.PutWithDeadline("b", 2, time.Now().Add(-time.Second))
+
+	if v, ok := cache.Pop("a"); !ok || v != 1 {
+		t.Errorf("Pop returned %v, %v, expected 1, true", v, ok)
+	}
+
+	if _, ok := cache.Pop("a"); ok {
+		t.Errorf("Key popped twice")
+	}
+
+	if _, ok := cache.Pop("b"); ok || cache.Exists("b") {
+		t.Errorf("Pop returned an expired value")
+	}
+}
+
+func TestWatermarks(t *testing.T) {
+	cache := Cache{Duration: 60, HighWatermark: 10, LowWatermark: 6}
+	cache.Init()
+
+	for i := 0; i < 10; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+
+	if cache.Count() != 10 {
+		t.Errorf("Evicted before reaching the high watermark")
+	}
+
+	// crossing the high watermark evicts down to the low one
+	cache.Put("a", 1)
+	if cache.Count() != 7 {
+		t.Errorf("Count is %d after eviction, expected 7", cache.Count())
+	}
+}
+
+func TestCountConcurrent(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				key := fmt.Sprint(i, ":", j)
+				cache.Put(key, j)
+				cache.Count()
+				if j%2 == 0 {
+					cache.Del(key)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if cache.Count() != 200 {
+		t.Errorf("Count returned %d, expected 200", cache.Count())
+	}
+	if cache.Count() != cache.data.Len() {
+		t.Errorf("Count %d doesn't match the %d keys stored", cache.Count(),
+			cache.data.Len())
+	}
+}
+
+func TestTryGetPut(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	if err := cache.TryPut("a", 1); err != nil {
+		t.Fatalf("TryPut failed: %v", err)
+	}
+	if v, err := cache.TryGet("a"); v != 1 || err != nil {
+		t.Errorf("TryGet returned %v, %v", v, err)
+	}
+
+	cache.Lock()
+	if _, err := cache.TryGet("a"); err != ErrLocked {
+		t.Errorf("TryGet of locked cache returned %v", err)
+	}
+	if err := cache.TryPut("b", 2); err != ErrLocked {
+		t.Errorf("TryPut of locked cache returned %v", err)
+	}
+	cache.Unlock()
+
+	if cache.Exists("b") || cache.Contention() != 2 {
+		t.Errorf("Failed TryPut stored the key or wasn't counted")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("nil", nil)
+	if v, ok := cache.Lookup("nil"); v != nil || !ok {
+		t.Errorf("Lookup of nil value returned %v, %v", v, ok)
+	}
+	if v, ok := cache.Lookup("missing"); v != nil || ok {
+		t.Errorf("Lookup of missing key returned %v, %v", v, ok)
+	}
+}
+
+func TestIterSnapshot(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+
+	entries := cache.IterSnapshot()
+	cache.Put("0", -1)
+	cache.Put("new", 1)
+	cache.Del("1")
+
+	n := 0
+	for cv := range entries {
+		if cv.Key == "new" || (cv.Key == "0" && cv.Value != 0) {
+			t.Errorf("Snapshot saw a later write to %s", cv.Key)
+		}
+		n++
+	}
+	if n != 100 {
+		t.Errorf("Snapshot yielded %d entries, expected 100", n)
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkGet(b *testing.B) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	keys := benchmarkKeys(10000)
+	for _, key := range keys {
+		cache.Put(key, 1)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkPut(b *testing.B) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	keys := benchmarkKeys(b.N)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for _, key := range keys {
+		cache.Put(key, 1)
+	}
+}
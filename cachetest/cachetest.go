@@ -0,0 +1,173 @@
+// Package cachetest provides a clock for testing code using expiringcache
+// without waiting for keys to expire, and a suite checking that a cache
+// implementation follows the expiry semantics of expiringcache.Cache.
+package cachetest
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock is a clock that only moves when told to. Its Now method can be
+// used as the Clock of a Cache.
+type Clock struct {
+	now time.Time
+	sync.Mutex
+}
+
+// NewClock returns a clock set to start
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+func (c *Clock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d
+func (c *Clock) Advance(d time.Duration) {
+	c.Lock()
+	c.now = c.now.Add(d)
+	c.Unlock()
+}
+
+// Cache is the part of expiringcache.Cache checked by Run, which
+// expiringcache.ShardedCache also implements
+type Cache interface {
+	Get(key string) interface{}
+	Put(key string, value interface{})
+	PutWithExpiry(key string, value interface{}, duration int)
+	Del(key string)
+	DelValue(key string) (interface{}, bool)
+	Exists(key string) bool
+	Count() int
+	TTL(key string) (time.Duration, bool)
+	EvictExpired(maxEntries int, maxDuration time.Duration) int
+}
+
+// Factory returns a new, empty cache storing keys for 60 seconds by
+// default and telling the time with clock
+type Factory func(clock func() time.Time) Cache
+
+// DefaultDuration is the default duration in seconds of the caches
+// returned by a Factory
+const DefaultDuration = 60
+
+var conformanceTests = []struct {
+	name string
+	run  func(t *testing.T, c Cache, clock *Clock)
+}{
+	{"GetBeforeExpiry", func(t *testing.T, c Cache, clock *Clock) {
+		c.PutWithExpiry("a", 1, 10)
+		clock.Advance(9 * time.Second)
+		c.EvictExpired(0, 0)
+
+		if v := c.Get("a"); v != 1 {
+			t.Errorf("Get returned %v, expected 1", v)
+		}
+		if d, ok := c.TTL("a"); !ok || d != time.Second {
+			t.Errorf("TTL returned %v, %v, expected 1s", d, ok)
+		}
+	}},
+	{"ExpiredKeyRemoved", func(t *testing.T, c Cache, clock *Clock) {
+		c.PutWithExpiry("a", 1, 10)
+		clock.Advance(10 * time.Second)
+
+		if d, ok := c.TTL("a"); ok && d != 0 {
+			t.Errorf("TTL of expired key returned %v", d)
+		}
+		if n := c.EvictExpired(0, 0); n != 1 {
+			t.Errorf("EvictExpired removed %d keys, expected 1", n)
+		}
+		if c.Get("a") != nil || c.Exists("a") || c.Count() != 0 {
+			t.Errorf("Expired key still in the cache")
+		}
+	}},
+	{"NoExpiry", func(t *testing.T, c Cache, clock *Clock) {
+		c.PutWithExpiry("a", 1, 0)
+		clock.Advance(1000 * time.Hour)
+		c.EvictExpired(0, 0)
+
+		if c.Get("a") != 1 {
+			t.Errorf("Key without expiry was removed")
+		}
+		if d, ok := c.TTL("a"); !ok || d != expiringcache.NoExpiry {
+			t.Errorf("TTL returned %v, %v, expected NoExpiry", d, ok)
+		}
+	}},
+	{"DefaultDuration", func(t *testing.T, c Cache, clock *Clock) {
+		c.Put("a", 1)
+		clock.Advance((DefaultDuration - 1) * time.Second)
+		c.EvictExpired(0, 0)
+		if !c.Exists("a") {
+			t.Fatalf("Key removed before the default duration")
+		}
+
+		clock.Advance(time.Second)
+		c.EvictExpired(0, 0)
+		if c.Exists("a") {
+			t.Errorf("Key kept past the default duration")
+		}
+	}},
+	{"OverwriteRestartsExpiry", func(t *testing.T, c Cache, clock *Clock) {
+		c.PutWithExpiry("a", 1, 10)
+		clock.Advance(5 * time.Second)
+		c.PutWithExpiry("a", 2, 10)
+		clock.Advance(6 * time.Second)
+		c.EvictExpired(0, 0)
+
+		if c.Get("a") != 2 {
+			t.Errorf("Overwritten key expired with its old duration")
+		}
+		if d, _ := c.TTL("a"); d != 4*time.Second {
+			t.Errorf("TTL returned %v, expected 4s", d)
+		}
+	}},
+	{"EvictExpiredLimit", func(t *testing.T, c Cache, clock *Clock) {
+		for _, key := range []string{"a", "b", "c"} {
+			c.PutWithExpiry(key, key, 1)
+		}
+		c.PutWithExpiry("d", "d", 100)
+		clock.Advance(time.Second)
+
+		if n := c.EvictExpired(2, 0); n != 2 || c.Count() != 2 {
+			t.Errorf("EvictExpired(2) removed %d keys, leaving %d", n,
+				c.Count())
+		}
+		if n := c.EvictExpired(0, 0); n != 1 || c.Count() != 1 {
+			t.Errorf("EvictExpired removed %d keys, leaving %d", n, c.Count())
+		}
+	}},
+	{"Del", func(t *testing.T, c Cache, clock *Clock) {
+		c.Put("a", 1)
+		c.Put("b", 2)
+
+		c.Del("a")
+		if c.Exists("a") || c.Count() != 1 {
+			t.Errorf("Key still in the cache after Del")
+		}
+
+		if v, ok := c.DelValue("b"); v != 2 || !ok {
+			t.Errorf("DelValue returned %v, %v", v, ok)
+		}
+		if _, ok := c.DelValue("b"); ok || c.Exists("b") || c.Count() != 0 {
+			t.Errorf("Key still in the cache after DelValue")
+		}
+	}},
+}
+
+// Run checks that the caches returned by newCache behave like
+// expiringcache.Cache as far as expiry goes, running each check as a
+// subtest on a new cache
+func Run(t *testing.T, newCache Factory) {
+	for _, test := range conformanceTests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := NewClock(time.Unix(1700000000, 0))
+			test.run(t, newCache(clock.Now), clock)
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package cachetest
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	Run(t, func(clock func() time.Time) Cache {
+		c := &expiringcache.Cache{Duration: DefaultDuration, Clock: clock}
+		c.Init()
+		return c
+	})
+}
+
+func TestShardedCache(t *testing.T) {
+	Run(t, func(clock func() time.Time) Cache {
+		c := &expiringcache.ShardedCache{Shards: 4,
+			NewShard: func() *expiringcache.Cache {
+				return &expiringcache.Cache{Duration: DefaultDuration,
+					Clock: clock}
+			}}
+		c.Init()
+		return c
+	})
+}
+
+func TestClock(t *testing.T) {
+	start := time.Unix(100, 0)
+	clock := NewClock(start)
+	clock.Advance(time.Minute)
+
+	if !clock.Now().Equal(start.Add(time.Minute)) {
+		t.Errorf("Clock reads %v after advancing a minute", clock.Now())
+	}
+}
@@ -0,0 +1,23 @@
+package expiringcache
+
+// clone returns the copy of a stored value handed out to callers: decoded
+// if Codec is set, copied by CloneFunc if that is set, or value itself
+func (p *Cache) clone(value interface{}) interface{} {
+	if p.Codec != nil {
+		b, ok := value.([]byte)
+		if !ok {
+			return nil
+		}
+
+		v, err := p.Codec.Decode(b)
+		if err != nil {
+			return nil
+		}
+		return v
+	}
+
+	if p.CloneFunc == nil || value == nil {
+		return value
+	}
+	return p.CloneFunc(value)
+}
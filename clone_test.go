@@ -0,0 +1,25 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestCloneFunc(t *testing.T) {
+	cache := Cache{Duration: 60, CloneFunc: func(v interface{}) interface{} {
+		return append([]int(nil), v.([]int)...)
+	}}
+	cache.Init()
+
+	cache.Put("a", []int{1, 2})
+	cache.Get("a").([]int)[0] = 5
+
+	if v := cache.Get("a").([]int); v[0] != 1 {
+		t.Errorf("Cached value modified through Get: %v", v)
+	}
+
+	cv, _ := cache.GetEntry("a")
+	cv.Value.([]int)[0] = 5
+	if v := cache.Get("a").([]int); v[0] != 1 {
+		t.Errorf("Cached value modified through GetEntry: %v", v)
+	}
+}
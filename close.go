@@ -0,0 +1,67 @@
+package expiringcache
+
+import (
+	"context"
+)
+
+// Close shuts the cache down so a restart doesn't lose its state. It stops
+// the periodic eviction, writes every entry to DumpOnClose if set and then
+// removes all entries, publishing EventClear for each so subscribers can
+// release them, before ending all subscriptions. Writes are refused from
+// the start, so none are lost from the dump, and afterwards writes, Fetch
+// and Remove return ErrClosed and the cache stays empty.
+func (p *Cache) Close() error {
+	var err error
+	p.stopOnce.Do(func() {
+		p.Lock()
+		p.closed = true
+		p.Unlock()
+
+		// a cache that was never initialized has nothing to release
+		if p.stop == nil {
+			return
+		}
+
+		close(p.stop)
+		if p.Sweeper != nil {
+			p.Sweeper.remove(p)
+		}
+
+		if p.DumpOnClose != nil {
+			err = p.Dump(p.DumpOnClose, FormatJSON)
+			if err != nil {
+				p.logger().Error("expiringcache: dump on close failed",
+					"err", err)
+			}
+		}
+
+		p.Lock()
+		for p.data.Len() > 0 {
+			p.remove(p.data.At(0).(*CacheValue), EventClear)
+		}
+		for s := range p.subs {
+			p.unsubscribe(s)
+		}
+		p.Unlock()
+	})
+
+	return err
+}
+
+// Drain hands the cache over to its replacement, e.g. when a service
+// instance is shutting down: writes are refused with ErrClosed from then
+// on, a snapshot of the remaining entries is saved to store as with SaveTo,
+// for the replacement to load with LoadFrom, and the cache is closed.
+// Reads keep working until the cache is closed, which happens even if the
+// snapshot can't be saved.
+func (p *Cache) Drain(ctx context.Context, store SnapshotStore) error {
+	p.Lock()
+	p.closed = true
+	p.Unlock()
+
+	err := p.SaveTo(ctx, store)
+	if cerr := p.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
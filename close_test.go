@@ -0,0 +1,149 @@
+package expiringcache
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+	var buf bytes.Buffer
+	cache := Cache{Duration: 60, PeriodicEvictionInterval: 1,
+		DumpOnClose: &buf}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	events, _ := cache.Subscribe()
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if buf.String() != "{\"key\":\"a\",\"ttl\":60,\"value\":1}\n"+
+		"{\"key\":\"b\",\"ttl\":60,\"value\":2}\n" {
+		t.Errorf("Unexpected dump on close:\n%s", buf.String())
+	}
+
+	n := 0
+	for e := range events {
+		if e.Type != EventClear {
+			t.Errorf("Unexpected %v event on close", e.Type)
+		}
+		n++
+	}
+
+	if n != 2 || cache.Count() != 0 {
+		t.Errorf("Close evicted %d keys, %d remain", n, cache.Count())
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Errorf("Second Close failed: %v", err)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	store := FileStore{Path: filepath.Join(t.TempDir(), "cache")}
+
+	old := Cache{Duration: 60}
+	old.Init()
+	old.Put("a", 1)
+	old.Put("b", 2)
+
+	if err := old.Drain(context.Background(), store); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if err := old.Set("c", 3); err != ErrClosed {
+		t.Errorf("Put after Drain returned %v", err)
+	}
+
+	replacement := Cache{Duration: 60}
+	replacement.Init()
+	if n, err := replacement.LoadFrom(context.Background(), store); n != 2 ||
+		err != nil {
+		t.Errorf("LoadFrom returned %d, %v", n, err)
+	}
+	if v := replacement.Get("b"); v != 2 {
+		t.Errorf("Handed over b as %v", v)
+	}
+}
+
+func TestWritesRefusedWhileDraining(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+	cache.Put("a", 1)
+	cache.HSet("h", "f", 1)
+	_, version := cache.GetWithVersion("a")
+
+	// as Drain does before taking its snapshot
+	cache.Lock()
+	cache.closed = true
+	cache.Unlock()
+
+	err := cache.Update("a", func(old interface{}, exists bool) (interface{},
+		bool) {
+		return 2, true
+	})
+	if err != ErrClosed {
+		t.Errorf("Update returned %v", err)
+	}
+	if cache.CompareAndSwap("a", version, 3) {
+		t.Errorf("CompareAndSwap succeeded")
+	}
+	if err := cache.HSet("h", "f", 2); err != ErrClosed {
+		t.Errorf("HSet returned %v", err)
+	}
+	if cache.HDel("h", "f") {
+		t.Errorf("HDel succeeded")
+	}
+
+	if v := cache.Get("a"); v != 1 {
+		t.Errorf("a changed to %v", v)
+	}
+	if v, _ := cache.HGet("h", "f"); v != 1 {
+		t.Errorf("h changed to %v", v)
+	}
+}
+
+func TestCloseUninitialized(t *testing.T) {
+	var cache Cache
+	if err := cache.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if err := cache.Set("a", 1); err != ErrClosed {
+		t.Errorf("Set after Close returned %v", err)
+	}
+}
+
+// writeOnDump writes to cache when the dump starts
+type writeOnDump struct {
+	cache *Cache
+	err   error
+	bytes.Buffer
+}
+
+func (p *writeOnDump) Write(b []byte) (int, error) {
+	if p.Len() == 0 {
+		p.err = p.cache.Set("b", 2)
+	}
+	return p.Buffer.Write(b)
+}
+
+func TestCloseRefusesWritesDuringDump(t *testing.T) {
+	cache := Cache{}
+	w := &writeOnDump{cache: &cache}
+	cache.DumpOnClose = w
+	cache.Init()
+	cache.Put("a", 1)
+
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if w.err != ErrClosed {
+		t.Errorf("Set during the dump returned %v", w.err)
+	}
+	if w.String() != "{\"key\":\"a\",\"ttl\":-1,\"value\":1}\n" {
+		t.Errorf("Unexpected dump:\n%s", w.String())
+	}
+}
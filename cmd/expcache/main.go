@@ -0,0 +1,148 @@
+// Command expcache inspects files written by Cache.Dump in FormatJSON.
+//
+//	expcache [-prefix P] list FILE      list keys and their TTLs
+//	expcache show FILE KEY              print the record of KEY
+//	expcache [-prefix P] del FILE [KEY...]
+//	                                    delete KEYs (or all keys matching
+//	                                    -prefix) from FILE in place
+//
+// TTLs are the seconds left at the time the dump was written.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/deep-compute/expiringcache"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: expcache [-prefix P] list FILE\n"+
+		"       expcache show FILE KEY\n"+
+		"       expcache [-prefix P] del FILE [KEY...]\n")
+	flag.PrintDefaults()
+	os.Exit(2)
+}
+
+func main() {
+	prefix := flag.String("prefix", "", "only consider keys with this prefix")
+	flag.Usage = usage
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) < 2 {
+		usage()
+	}
+
+	records, err := readDump(args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "expcache:", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		w := bufio.NewWriter(os.Stdout)
+		for _, r := range records {
+			if strings.HasPrefix(r.Key, *prefix) {
+				fmt.Fprintf(w, "%s\t%d\n", r.Key, r.TTL)
+			}
+		}
+		w.Flush()
+
+	case "show":
+		if len(args) != 3 {
+			usage()
+		}
+
+		for _, r := range records {
+			if r.Key == args[2] {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				enc.Encode(r)
+				return
+			}
+		}
+
+		fmt.Fprintln(os.Stderr, "expcache: key not found:", args[2])
+		os.Exit(1)
+
+	case "del":
+		if len(args) == 2 && *prefix == "" {
+			usage()
+		}
+
+		del := make(map[string]bool)
+		for _, key := range args[2:] {
+			del[key] = true
+		}
+
+		kept := records[:0]
+		for _, r := range records {
+			if del[r.Key] || (len(del) == 0 && strings.HasPrefix(r.Key, *prefix)) {
+				continue
+			}
+			kept = append(kept, r)
+		}
+
+		if err := writeDump(args[1], kept); err != nil {
+			fmt.Fprintln(os.Stderr, "expcache:", err)
+			os.Exit(1)
+		}
+
+	default:
+		usage()
+	}
+}
+
+func readDump(path string) ([]expiringcache.DumpRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []expiringcache.DumpRecord
+	dec := json.NewDecoder(f)
+	for {
+		var r expiringcache.DumpRecord
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, r)
+	}
+}
+
+// writeDump replaces the file at path atomically
+func writeDump(path string, records []expiringcache.DumpRecord) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".expcache")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	enc := json.NewEncoder(f)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,56 @@
+package expiringcache
+
+import (
+	"context"
+)
+
+// loadCall is a Loader call in progress, shared by concurrent misses of
+// the same key
+type loadCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// loadShared loads key with Loader unless another goroutine is already
+// loading it, in which case it waits for that load and reports shared.
+// Only the caller that did the load should store the value, so a burst of
+// misses makes a single call to the backing store. A waiting caller gives
+// up when ctx is done, but the load itself runs with the ctx of the caller
+// that started it. If Loader panics, the waiting callers get
+// ErrLoaderPanic.
+func (p *Cache) loadShared(ctx context.Context, key string) (value interface{},
+	shared bool, err error) {
+	key = p.canonicalKey(key)
+
+	p.Lock()
+	if call, ok := p.loads[key]; ok {
+		p.Unlock()
+
+		select {
+		case <-call.done:
+			return call.value, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+
+	call := &loadCall{done: make(chan struct{})}
+	if p.loads == nil {
+		p.loads = make(map[string]*loadCall)
+	}
+	p.loads[key] = call
+	p.Unlock()
+
+	// release the waiters even if Loader panics, with ErrLoaderPanic
+	call.err = ErrLoaderPanic
+	defer func() {
+		p.Lock()
+		delete(p.loads, key)
+		p.Unlock()
+		close(call.done)
+	}()
+
+	call.value, call.err = p.load(ctx, key)
+	return call.value, false, call.err
+}
@@ -0,0 +1,41 @@
+package expiringcache
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// Codec encodes values for caches storing them encoded (see Cache.Codec)
+type Codec interface {
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// GobCodec encodes values with encoding/gob. Concrete types stored in an
+// interface{} must be registered with gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) Encode(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&value)
+	return buf.Bytes(), err
+}
+
+func (GobCodec) Decode(data []byte) (interface{}, error) {
+	var value interface{}
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// encode returns value as stored by the cache, encoded if Codec is set
+func (p *Cache) encode(value interface{}) (interface{}, error) {
+	if p.Codec == nil {
+		return value, nil
+	}
+
+	b, err := p.Codec.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,28 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	cache := Cache{Duration: 60, Codec: GobCodec{}}
+	cache.Init()
+
+	cache.Put("a", []int{1, 2})
+	cache.Get("a").([]int)[0] = 5
+
+	if v := cache.Get("a").([]int); v[0] != 1 {
+		t.Errorf("Cached value modified through Get: %v", v)
+	}
+
+	cache.Update("a", func(old interface{}, ok bool) (interface{}, bool) {
+		return append(old.([]int), 3), true
+	})
+	if v := cache.Get("a").([]int); len(v) != 3 {
+		t.Errorf("Update stored %v", v)
+	}
+
+	if err := cache.Set("b", func() {}); err == nil {
+		t.Errorf("Value that can't be encoded was stored")
+	}
+}
@@ -0,0 +1,106 @@
+package expiringcache
+
+// ExpiringSet is a set of strings each kept for its own duration, e.g. to
+// drop duplicate messages seen recently. Members are stored as keys of
+// Cache, which may hold nothing else.
+type ExpiringSet struct {
+	Cache *Cache
+}
+
+// Add adds member for duration seconds, or restarts its duration if it is
+// already in the set, and reports whether it was added
+func (p *ExpiringSet) Add(member string, duration int) bool {
+	c := p.Cache
+	c.Lock()
+	defer c.Unlock()
+
+	v := c.find(c.canonicalKey(member))
+	added := v == nil || v.expired(c.now())
+
+	_, _, err := c.put(member, true, entryOptions{duration: duration})
+	return added && err == nil
+}
+
+// Contains reports whether member is in the set and hasn't expired
+func (p *ExpiringSet) Contains(member string) bool {
+	_, err := p.Cache.Fetch(member)
+	return err == nil
+}
+
+// Remove removes member and reports whether it was in the set
+func (p *ExpiringSet) Remove(member string) bool {
+	return p.Cache.Remove(member) == nil
+}
+
+// ExpiringCounter counts events per key over a sliding window of the last
+// Window seconds, e.g. to track request rates. Counts are kept per second
+// in Cache, which may hold nothing else, so a key takes memory in
+// proportion to Window and expires once it has had no events for Window
+// seconds. A Window of 0 or less counts events over a single second.
+type ExpiringCounter struct {
+	Cache  *Cache
+	Window int
+}
+
+// slidingCounts holds the count of each of the last seconds in slots
+// indexed by the second modulo the window
+type slidingCounts struct {
+	seconds []int64
+	counts  []int64
+}
+
+// sum returns the count of the window ending at second ts
+func (p slidingCounts) sum(ts int64) int64 {
+	var n int64
+	for i, s := range p.seconds {
+		if s > ts-int64(len(p.seconds)) && s <= ts {
+			n += p.counts[i]
+		}
+	}
+	return n
+}
+
+// Add counts n events for key now and returns the count over the window
+func (p *ExpiringCounter) Add(key string, n int64) int64 {
+	window := p.window()
+	ts := p.Cache.now()
+	slot := int(ts % int64(window))
+
+	var total int64
+	p.Cache.UpdateWithExpiry(key, window,
+		func(old interface{}, exists bool) (interface{}, bool) {
+			// values are replaced rather than modified in place
+			c := slidingCounts{seconds: make([]int64, window),
+				counts: make([]int64, window)}
+			if exists {
+				copy(c.seconds, old.(slidingCounts).seconds)
+				copy(c.counts, old.(slidingCounts).counts)
+			}
+
+			if c.seconds[slot] != ts {
+				c.seconds[slot], c.counts[slot] = ts, 0
+			}
+			c.counts[slot] += n
+			total = c.sum(ts)
+			return c, true
+		})
+
+	return total
+}
+
+// window returns Window, or 1 if it isn't set
+func (p *ExpiringCounter) window() int {
+	if p.Window > 0 {
+		return p.Window
+	}
+	return 1
+}
+
+// Count returns the count of key over the window
+func (p *ExpiringCounter) Count(key string) int64 {
+	v, ok := p.Cache.Lookup(key)
+	if !ok {
+		return 0
+	}
+	return v.(slidingCounts).sum(p.Cache.now())
+}
@@ -0,0 +1,75 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiringSet(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Clock: func() time.Time { return now }}
+	cache.Init()
+	set := ExpiringSet{Cache: &cache}
+
+	if !set.Add("a", 10) || set.Add("a", 10) {
+		t.Errorf("Add did not report new members")
+	}
+	set.Add("b", 30)
+	if !set.Contains("a") || set.Contains("c") {
+		t.Errorf("Contains is wrong")
+	}
+
+	now = now.Add(20 * time.Second)
+	if set.Contains("a") || !set.Contains("b") {
+		t.Errorf("Members did not expire on their own")
+	}
+	if !set.Add("a", 10) {
+		t.Errorf("Expired member not added again")
+	}
+	if !set.Remove("b") || set.Contains("b") {
+		t.Errorf("Remove failed")
+	}
+}
+
+func TestExpiringCounter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Clock: func() time.Time { return now }}
+	cache.Init()
+	counter := ExpiringCounter{Cache: &cache, Window: 10}
+
+	for i := 0; i < 10; i++ {
+		counter.Add("a", 1)
+		now = now.Add(time.Second)
+	}
+	// the window now covers 1001 to 1010
+	if n := counter.Count("a"); n != 9 {
+		t.Errorf("Count is %d, expected 9", n)
+	}
+	if n := counter.Add("a", 5); n != 14 {
+		t.Errorf("Add returned %d, expected 14", n)
+	}
+
+	now = now.Add(5 * time.Second)
+	if n := counter.Count("a"); n != 9 {
+		t.Errorf("Count is %d after the window moved, expected 9", n)
+	}
+	if n := counter.Count("b"); n != 0 {
+		t.Errorf("Count of a missing key is %d", n)
+	}
+}
+
+func TestExpiringCounterNoWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Clock: func() time.Time { return now }}
+	cache.Init()
+	counter := ExpiringCounter{Cache: &cache}
+
+	counter.Add("a", 1)
+	if n := counter.Add("a", 2); n != 3 {
+		t.Errorf("Add returned %d, expected 3", n)
+	}
+	now = now.Add(time.Second)
+	if n := counter.Add("a", 1); n != 1 {
+		t.Errorf("Add returned %d a second later, expected 1", n)
+	}
+}
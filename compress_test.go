@@ -0,0 +1,27 @@
+package expiringcache
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompression(t *testing.T) {
+	cache := BytesCache{Duration: 60, ArenaSize: 1024,
+		Compressor: &GzipCompressor{}, CompressThreshold: 100}
+	cache.Init()
+
+	// too large to fit uncompressed
+	large := bytes.Repeat([]byte("a"), 4096)
+	if err := cache.Put("large", large); err != nil {
+		t.Fatalf("Put of compressible value failed: %v", err)
+	}
+
+	if !bytes.Equal(cache.Get("large"), large) {
+		t.Errorf("Get did not return the decompressed value")
+	}
+
+	cache.Put("small", []byte("b"))
+	if !bytes.Equal(cache.Get("small"), []byte("b")) {
+		t.Errorf("Get did not return the value below threshold")
+	}
+}
@@ -0,0 +1,39 @@
+package expiringcache
+
+// Cost returns the total cost of the entries in the cache as counted
+// against MaxCost
+func (p *Cache) Cost() int64 {
+	p.Lock()
+	defer p.Unlock()
+	return p.cost
+}
+
+// costOf returns what value counts against MaxCost, 1 if there is no
+// CostFunc
+func (p *Cache) costOf(key string, value interface{}) int64 {
+	if p.CostFunc == nil {
+		return 1
+	}
+	return p.CostFunc(key, value)
+}
+
+// overCost reports whether adding an entry costing c would exceed MaxCost
+func (p *Cache) overCost(c int64) bool {
+	return p.MaxCost > 0 && p.cost+c > p.MaxCost
+}
+
+// setValue replaces the value of cv, keeping the total cost, indexes and
+// references up to date. It must be called with the lock held.
+func (p *Cache) setValue(cv *CacheValue, value interface{}) {
+	c := p.costOf(cv.Key, value)
+	p.cost += c - cv.cost
+	cv.cost = c
+	p.untrack(cv)
+	cv.Value = value
+	p.reindex(cv)
+	p.track(cv)
+
+	if p.CheckInvariants {
+		p.checkInvariants("setValue")
+	}
+}
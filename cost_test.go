@@ -0,0 +1,35 @@
+package expiringcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMaxCost(t *testing.T) {
+	cache := Cache{Duration: 60, MaxCost: 10, EvictSoonest: true,
+		CostFunc: func(key string, value interface{}) int64 {
+			return int64(len(value.([]byte)))
+		}}
+	cache.Init()
+
+	for i := 0; i < 3; i++ {
+		cache.PutWithExpiry(strconv.Itoa(i), make([]byte, 3), 10+i)
+	}
+
+	if cache.Cost() != 9 {
+		t.Errorf("Cost is %d, expected 9", cache.Cost())
+	}
+
+	// evicts the two soonest expiring keys to make room
+	cache.Put("a", make([]byte, 5))
+	if cache.Cost() != 8 || cache.Exists("0") || cache.Exists("1") {
+		t.Errorf("Cost is %d after eviction, expected 8", cache.Cost())
+	}
+
+	cache.Put("a", make([]byte, 1))
+	cache.Del("2")
+	if cache.Cost() != 1 {
+		t.Errorf("Cost is %d after update and delete, expected 1",
+			cache.Cost())
+	}
+}
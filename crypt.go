@@ -0,0 +1,181 @@
+package expiringcache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+)
+
+// size of the plaintext sealed in each chunk by EncryptWriter
+const cryptChunkSize = 64 << 10
+
+// The stream written by EncryptWriter is a random nonce prefix followed by
+// chunks, each a 4 byte big-endian length and up to cryptChunkSize bytes
+// sealed with AES-GCM. Each chunk's nonce is the prefix, the chunk number
+// and a flag set only on the last chunk, so chunks can't be reordered,
+// dropped or cut off at the end without failing to decrypt.
+const cryptPrefixSize = 7
+
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix [cryptPrefixSize]byte
+	n      uint32
+	buf    []byte
+}
+
+// EncryptWriter returns a writer encrypting everything written to it with
+// AES-GCM and key, which must be 16, 24 or 32 bytes long, before writing
+// it to w, e.g. to Dump a cache holding session tokens to disk. It must be
+// closed to write the end of the data; this doesn't close w. Read the data
+// back with DecryptReader.
+func EncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &encryptWriter{w: w, aead: aead,
+		buf: make([]byte, 0, cryptChunkSize)}
+	if _, err := rand.Read(e.prefix[:]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(e.prefix[:]); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// cryptNonce returns the nonce of chunk n
+func cryptNonce(prefix [cryptPrefixSize]byte, n uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[cryptPrefixSize:], n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func (p *encryptWriter) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		if len(p.buf) == cryptChunkSize {
+			if err := p.flush(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(p.buf[len(p.buf):cryptChunkSize], b)
+		p.buf = p.buf[:len(p.buf)+n]
+		b = b[n:]
+		written += n
+	}
+
+	return written, nil
+}
+
+// flush seals and writes the buffered chunk
+func (p *encryptWriter) flush(last bool) error {
+	sealed := p.aead.Seal(nil, cryptNonce(p.prefix, p.n, last), p.buf, nil)
+	p.n++
+	p.buf = p.buf[:0]
+
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := p.w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := p.w.Write(sealed)
+	return err
+}
+
+// Close writes the last chunk
+func (p *encryptWriter) Close() error {
+	return p.flush(true)
+}
+
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	prefix [cryptPrefixSize]byte
+	n      uint32
+	buf    []byte
+	done   bool
+}
+
+// DecryptReader returns a reader of the data written to r by EncryptWriter
+// with the same key. Reads fail with ErrDecrypt if the data was modified or
+// is incomplete.
+func DecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &decryptReader{r: r, aead: aead}
+	if _, err := io.ReadFull(r, d.prefix[:]); err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return d, nil
+}
+
+func (p *decryptReader) Read(b []byte) (int, error) {
+	for len(p.buf) == 0 {
+		if p.done {
+			return 0, io.EOF
+		}
+		if err := p.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+// next reads and opens the next chunk
+func (p *decryptReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(p.r, size[:]); err != nil {
+		return ErrDecrypt
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > cryptChunkSize+uint32(p.aead.Overhead()) {
+		return ErrDecrypt
+	}
+
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(p.r, sealed); err != nil {
+		return ErrDecrypt
+	}
+
+	plain, err := p.aead.Open(nil, cryptNonce(p.prefix, p.n, false), sealed,
+		nil)
+	if err != nil {
+		plain, err = p.aead.Open(nil, cryptNonce(p.prefix, p.n, true), sealed,
+			nil)
+		if err != nil {
+			return ErrDecrypt
+		}
+		p.done = true
+	}
+
+	p.n++
+	p.buf = plain
+	return nil
+}
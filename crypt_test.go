@@ -0,0 +1,96 @@
+package expiringcache
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncrypt(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+
+	cache := Cache{Duration: 60}
+	cache.Init()
+	cache.Put("token", "secret-value")
+	cache.Put("big", strings.Repeat("x", 3*cryptChunkSize))
+
+	var buf bytes.Buffer
+	w, err := EncryptWriter(&buf, key)
+	if err != nil {
+		t.Fatalf("EncryptWriter failed: %v", err)
+	}
+	if err := cache.Dump(w, FormatJSON); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	w.Close()
+
+	if bytes.Contains(buf.Bytes(), []byte("secret-value")) {
+		t.Errorf("Encrypted data contains plaintext")
+	}
+
+	r, err := DecryptReader(bytes.NewReader(buf.Bytes()), key)
+	if err != nil {
+		t.Fatalf("DecryptReader failed: %v", err)
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Decrypting failed: %v", err)
+	}
+
+	var expected bytes.Buffer
+	cache.Dump(&expected, FormatJSON)
+	if !bytes.Equal(plain, expected.Bytes()) {
+		t.Errorf("Decrypted data doesn't match the dump")
+	}
+
+	// truncated at a chunk boundary, modified and with the wrong key
+	chunk := cryptPrefixSize + 4 + cryptChunkSize + 16
+	bad := [][]byte{buf.Bytes()[:chunk], append([]byte{}, buf.Bytes()...)}
+	bad[1][20] ^= 1
+	for i, data := range bad {
+		r, _ := DecryptReader(bytes.NewReader(data), key)
+		if _, err := io.ReadAll(r); err != ErrDecrypt {
+			t.Errorf("Reading bad data %d returned %v", i, err)
+		}
+	}
+
+	r, _ = DecryptReader(bytes.NewReader(buf.Bytes()), bytes.Repeat([]byte{2}, 32))
+	if _, err := io.ReadAll(r); err != ErrDecrypt {
+		t.Errorf("Reading with the wrong key returned %v", err)
+	}
+}
+
+func FuzzDecryptReader(f *testing.F) {
+	key := bytes.Repeat([]byte{1}, 32)
+
+	cache := Cache{Duration: 60}
+	cache.Init()
+	cache.Put("token", "secret-value")
+
+	var buf bytes.Buffer
+	w, err := EncryptWriter(&buf, key)
+	if err != nil {
+		f.Fatalf("EncryptWriter failed: %v", err)
+	}
+	cache.Dump(w, FormatJSON)
+	w.Close()
+
+	f.Add(buf.Bytes())
+	f.Add(buf.Bytes()[:buf.Len()-1])
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r, err := DecryptReader(bytes.NewReader(data), key)
+		if err != nil {
+			if err != ErrDecrypt {
+				t.Errorf("DecryptReader returned %v", err)
+			}
+			return
+		}
+
+		if _, err := io.ReadAll(r); err != nil && err != ErrDecrypt {
+			t.Errorf("Reading returned %v", err)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package expiringcache
+
+import (
+	"context"
+	"time"
+)
+
+// GetCtx is like Get but returns ctx's error if it is already done. If the
+// key is missing and a Loader is set, the value is loaded with ctx and
+// stored as with Put. Concurrent misses of the same key share one Loader
+// call and its value.
+func (p *Cache) GetCtx(ctx context.Context, key string) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	_, end := p.trace(ctx, "get", key)
+	start := time.Now()
+	v, ok := p.Lookup(key)
+	if p.latency != nil {
+		p.observe(&p.latency.get, start)
+	}
+	end(ok, nil)
+
+	if ok || p.Loader == nil {
+		return v, nil
+	}
+
+	lctx, end := p.trace(ctx, "load", key)
+	v, shared, err := p.loadShared(lctx, key)
+	end(false, err)
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		return v, nil
+	}
+
+	if err := p.putLoaded(key, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// PutCtx is like Put but returns ctx's error, without storing the value,
+// if it is already done. The entry is labelled with the origin set on ctx
+// with WithOrigin, if any.
+func (p *Cache) PutCtx(ctx context.Context, key string, value interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, end := p.trace(ctx, "put", key)
+	var err error
+	if origin := originOf(ctx); origin != "" {
+		err = p.putFrom(origin, key, value, p.defaultOptions)
+	} else {
+		err = p.Set(key, value)
+	}
+	end(false, err)
+	return err
+}
+
+func (p *Cache) PutWithExpiryCtx(ctx context.Context, key string,
+	value interface{}, duration int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, end := p.trace(ctx, "put", key)
+	var err error
+	if origin := originOf(ctx); origin != "" {
+		err = p.putFrom(origin, key, value,
+			func(string, interface{}) entryOptions {
+				return entryOptions{duration: duration}
+			})
+	} else {
+		err = p.SetWithExpiry(key, value, duration)
+	}
+	end(false, err)
+	return err
+}
@@ -0,0 +1,154 @@
+package expiringcache
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetCtx(t *testing.T) {
+	loads := 0
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			loads++
+			if key == "missing" {
+				return nil, errors.New("not found")
+			}
+			return key + "!", nil
+		}}
+	cache.Init()
+
+	for i := 0; i < 2; i++ {
+		v, err := cache.GetCtx(context.Background(), "a")
+		if err != nil || v.(string) != "a!" {
+			t.Errorf("GetCtx returned %v, %v", v, err)
+		}
+	}
+
+	if loads != 1 {
+		t.Errorf("Loader called %d times, expected once", loads)
+	}
+
+	// a stored nil is a hit
+	cache.Put("nil", nil)
+	if v, err := cache.GetCtx(context.Background(), "nil"); v != nil ||
+		err != nil || loads != 1 {
+		t.Errorf("GetCtx of nil value returned %v, %v after %d loads", v,
+			err, loads)
+	}
+
+	if _, err := cache.GetCtx(context.Background(), "missing"); err == nil {
+		t.Errorf("Loader error not returned")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := cache.GetCtx(ctx, "b"); err != context.Canceled {
+		t.Errorf("GetCtx returned %v with cancelled context", err)
+	}
+
+	if err := cache.PutCtx(ctx, "c", 1); err != context.Canceled ||
+		cache.Exists("c") {
+		t.Errorf("PutCtx stored value with cancelled context")
+	}
+}
+
+func TestGetCtxCoalesces(t *testing.T) {
+	var loads int32
+	release := make(chan struct{})
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			atomic.AddInt32(&loads, 1)
+			<-release
+			return key + "!", nil
+		}}
+	cache.Init()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := cache.GetCtx(context.Background(), "a")
+			if v != "a!" || err != nil {
+				t.Errorf("GetCtx returned %v, %v", v, err)
+			}
+		}()
+	}
+
+	// let the callers pile up behind the first load
+	for {
+		cache.Lock()
+		_, loading := cache.loads["a"]
+		cache.Unlock()
+		if loading {
+			break
+		}
+		runtime.Gosched()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Errorf("Loader called %d times, expected 1", n)
+	}
+	if v := cache.Get("a"); v != "a!" {
+		t.Errorf("Loaded value not stored: %v", v)
+	}
+}
+
+func TestGetCtxLoaderPanic(t *testing.T) {
+	var loads int32
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			if atomic.AddInt32(&loads, 1) == 1 {
+				close(loading)
+				<-release
+				panic("load failed")
+			}
+			return key + "!", nil
+		}}
+	cache.Init()
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		cache.GetCtx(context.Background(), "a")
+	}()
+	<-loading
+
+	waited := make(chan error)
+	go func() {
+		_, err := cache.GetCtx(context.Background(), "a")
+		waited <- err
+	}()
+
+	// let the second caller wait for the first load
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if r := <-panicked; r == nil {
+		t.Errorf("Loader panic not passed on")
+	}
+	select {
+	case err := <-waited:
+		if err != ErrLoaderPanic {
+			t.Errorf("Waiting caller got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Waiting caller blocked after Loader panicked")
+	}
+
+	if v, err := cache.GetCtx(context.Background(), "a"); v != "a!" ||
+		err != nil {
+		t.Errorf("GetCtx after a panic returned %v, %v", v, err)
+	}
+}
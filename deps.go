@@ -0,0 +1,70 @@
+package expiringcache
+
+// PutWithDeps is like PutWithExpiry but also makes key depend on the keys
+// deps, e.g. a value computed from them: when any of them is removed, by
+// Del, expiry, eviction or in turn because of its own dependencies, key is
+// removed too with EventDelete. Dependencies that are not in the cache
+// still count should they be added and removed later. The next Put or
+// PutWithDeps of key replaces its dependencies; refreshes and Update keep
+// them.
+func (p *Cache) PutWithDeps(key string, value interface{}, duration int,
+	deps ...string) error {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	canonical := make([]string, len(deps))
+	for i, dep := range deps {
+		canonical[i] = p.canonicalKey(dep)
+	}
+
+	p.Lock()
+	_, _, err = p.put(key, value, entryOptions{duration: duration,
+		deps: canonical})
+	p.Unlock()
+	return err
+}
+
+// depend replaces the dependencies of cv with deps. It must be called with
+// the lock held.
+func (p *Cache) depend(cv *CacheValue, deps []string) {
+	p.undepend(cv)
+	if len(deps) == 0 {
+		return
+	}
+
+	if p.dependents == nil {
+		p.dependents = make(map[string]map[string]struct{})
+	}
+	for _, dep := range deps {
+		if p.dependents[dep] == nil {
+			p.dependents[dep] = make(map[string]struct{})
+		}
+		p.dependents[dep][cv.Key] = struct{}{}
+	}
+	cv.deps = deps
+}
+
+// undepend drops the dependencies of cv
+func (p *Cache) undepend(cv *CacheValue) {
+	for _, dep := range cv.deps {
+		delete(p.dependents[dep], cv.Key)
+		if len(p.dependents[dep]) == 0 {
+			delete(p.dependents, dep)
+		}
+	}
+	cv.deps = nil
+}
+
+// invalidateDependents removes the keys depending on key, which was
+// removed, and so on transitively
+func (p *Cache) invalidateDependents(key string) {
+	// removing a dependent drops it from the map, which is deleted once
+	// empty
+	for k := range p.dependents[key] {
+		if v := p.find(k); v != nil {
+			p.remove(v, EventDelete)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package expiringcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPutWithDeps(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("user", 1)
+	cache.PutWithDeps("profile", 2, 60, "user")
+	cache.PutWithDeps("page", 3, 60, "profile")
+	cache.PutWithDeps("other", 4, 60, "missing")
+
+	cache.Del("user")
+	for _, key := range []string{"user", "profile", "page"} {
+		if _, err := cache.Fetch(key); err != ErrNotFound {
+			t.Errorf("%s not invalidated: %v", key, err)
+		}
+	}
+	if _, err := cache.Fetch("other"); err != nil {
+		t.Errorf("other removed: %v", err)
+	}
+
+	// a later write drops the dependencies
+	cache.Put("user", 1)
+	cache.PutWithDeps("profile", 2, 60, "user")
+	cache.Put("profile", 5)
+	cache.Del("user")
+	if _, err := cache.Fetch("profile"); err != nil {
+		t.Errorf("profile removed after its dependencies were replaced: %v",
+			err)
+	}
+
+	// cycles end
+	cache.PutWithDeps("a", 1, 60, "b")
+	cache.PutWithDeps("b", 2, 60, "a")
+	cache.Del("a")
+	if cache.Count() != 2 {
+		t.Errorf("expected 2 keys, got %d", cache.Count())
+	}
+}
+
+func TestPutWithDepsExpiry(t *testing.T) {
+	now := time.Now()
+	cache := Cache{Duration: 60, Clock: func() time.Time { return now }}
+	cache.Init()
+
+	cache.PutWithDeps("derived", 2, 600, "base")
+	cache.Put("base", 1)
+
+	now = now.Add(2 * time.Minute)
+	cache.EvictExpired(0, 0)
+	if _, err := cache.Fetch("derived"); err != ErrNotFound {
+		t.Errorf("derived not invalidated by expiry: %v", err)
+	}
+}
+
+func TestDepsKeptAcrossUpdates(t *testing.T) {
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			return 2, nil
+		}}
+	cache.Init()
+
+	cache.Put("user", 1)
+	cache.PutWithDeps("profile", 1, 60, "user")
+	cache.PutWithDeps("page", 1, 60, "user")
+
+	cache.Lock()
+	version := cache.find("profile").Version
+	cache.Unlock()
+	cache.refresh("profile", version)
+
+	cache.UpdateWithExpiry("page", 120,
+		func(old interface{}, exists bool) (interface{}, bool) {
+			return 2, true
+		})
+
+	cache.Del("user")
+	for _, key := range []string{"profile", "page"} {
+		if _, err := cache.Fetch(key); err != ErrNotFound {
+			t.Errorf("%s lost its dependencies: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package expiringcache
+
+import (
+	"sync/atomic"
+)
+
+// Diagnostics is the overhead of the cache itself, beyond its entries
+type Diagnostics struct {
+	// Estimated bytes held by the indexes, dependencies and tombstones
+	IndexBytes int64
+	// Goroutines started by the cache that are still running: the
+	// periodic eviction, refreshes, Iter channels not read to the end and
+	// snapshots being written with SaveTo
+	Goroutines int
+	// Pending ExactExpiry timers
+	ExpiryTimers int
+	// Loader calls in progress for GetCtx and GetManyCtx
+	Loads int
+	// Subscriptions and the events queued for them but not yet received
+	Subscribers  int
+	EventBacklog int
+}
+
+// rough bytes per map entry and per string header, for the estimates
+const (
+	mapEntryBytes = 48
+	stringBytes   = 16
+)
+
+// Diagnostics returns the overhead of the cache, so operators can check its
+// own footprint
+func (p *Cache) Diagnostics() Diagnostics {
+	p.Lock()
+	defer p.Unlock()
+
+	d := Diagnostics{Goroutines: int(atomic.LoadInt32(&p.goroutines)),
+		Loads: len(p.loads), Subscribers: len(p.subs)}
+
+	for _, idx := range p.indexes {
+		for v, entries := range idx.entries {
+			d.IndexBytes += mapEntryBytes + stringBytes + int64(len(v))
+			for key := range entries {
+				d.IndexBytes += mapEntryBytes + stringBytes + int64(len(key))
+			}
+		}
+	}
+	for key, dependents := range p.dependents {
+		d.IndexBytes += mapEntryBytes + stringBytes + int64(len(key))
+		for k := range dependents {
+			d.IndexBytes += mapEntryBytes + stringBytes + int64(len(k))
+		}
+	}
+	for key := range p.tombstones {
+		d.IndexBytes += mapEntryBytes + stringBytes + int64(len(key))
+	}
+
+	for i := 0; i < p.data.Len(); i++ {
+		if p.data.At(i).(*CacheValue).timer != nil {
+			d.ExpiryTimers++
+		}
+	}
+	d.ExpiryTimers += len(p.buckets)
+
+	for s := range p.subs {
+		d.EventBacklog += len(s.c)
+	}
+
+	return d
+}
+
+// background runs f on a goroutine counted by Diagnostics
+func (p *Cache) background(f func()) {
+	atomic.AddInt32(&p.goroutines, 1)
+	go func() {
+		defer atomic.AddInt32(&p.goroutines, -1)
+		f()
+	}()
+}
@@ -0,0 +1,49 @@
+package expiringcache
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	cache := Cache{Duration: 60, PeriodicEvictionInterval: 60,
+		ExactExpiry: true, TombstoneDuration: 60,
+		Indexes: map[string]func(interface{}) string{
+			"value": func(v interface{}) string { return v.(string) }}}
+	cache.Init()
+	defer cache.Close()
+
+	events, unsubscribe := cache.Subscribe()
+	defer unsubscribe()
+
+	cache.Put("a", "x")
+	cache.PutWithExpiry("b", "y", 0)
+	cache.Put("c", "z")
+	cache.Del("c")
+	entries := cache.Iter()
+
+	d := cache.Diagnostics()
+	if d.Goroutines != 2 {
+		t.Errorf("Goroutines is %d, expected 2", d.Goroutines)
+	}
+	if d.ExpiryTimers != 1 {
+		t.Errorf("ExpiryTimers is %d, expected 1", d.ExpiryTimers)
+	}
+	if d.Subscribers != 1 || d.EventBacklog != 4 {
+		t.Errorf("%d subscribers with %d events queued", d.Subscribers,
+			d.EventBacklog)
+	}
+	if d.IndexBytes == 0 {
+		t.Errorf("IndexBytes not estimated")
+	}
+
+	for range entries {
+	}
+	<-events
+	for cache.Diagnostics().Goroutines != 1 {
+		runtime.Gosched()
+	}
+	if d := cache.Diagnostics(); d.EventBacklog != 3 {
+		t.Errorf("EventBacklog is %d after a receive", d.EventBacklog)
+	}
+}
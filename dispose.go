@@ -0,0 +1,88 @@
+package expiringcache
+
+import "sync/atomic"
+
+// valueRef counts the references to a stored value: one held by the cache
+// while the value is stored and one per unreleased Handle
+type valueRef struct {
+	value interface{}
+	refs  int32
+}
+
+// Handle keeps a value returned by Acquire from being disposed of until it
+// is released
+type Handle struct {
+	cache    *Cache
+	ref      *valueRef
+	released uint32
+}
+
+// Value returns the value as stored, without passing it through CloneFunc
+// or Codec, as the point of a handle is to use the stored value safely
+func (h *Handle) Value() interface{} {
+	return h.ref.value
+}
+
+// Release gives up the handle. Once the value has left the cache and all
+// its handles are released, it is passed to Disposer. Releasing a handle
+// again does nothing.
+func (h *Handle) Release() {
+	if atomic.CompareAndSwapUint32(&h.released, 0, 1) {
+		h.cache.unref(h.ref)
+	}
+}
+
+// Acquire returns a handle to the value of key, and false if it is not in
+// the cache. Unlike with Get, the value isn't disposed of while the handle
+// is held, even if it leaves the cache. Every handle must be released.
+// Without a Disposer, Acquire is like Get returning the stored value.
+func (p *Cache) Acquire(key string) (*Handle, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	cv := p.access(key)
+	if cv == nil {
+		return nil, false
+	}
+
+	ref := cv.ref
+	if ref == nil {
+		ref = &valueRef{value: cv.Value, refs: 1}
+	} else {
+		atomic.AddInt32(&ref.refs, 1)
+	}
+	return &Handle{cache: p, ref: ref}, true
+}
+
+// track starts counting references to the value of cv, if there is a
+// Disposer. It must be called with the lock held when the value is set.
+func (p *Cache) track(cv *CacheValue) {
+	if p.Disposer != nil {
+		cv.ref = &valueRef{value: cv.Value, refs: 1}
+	}
+}
+
+// handOver gives the reference of the cache to the value of cv to the
+// caller it is returned to, so it is never disposed of, unless the caller
+// gets a copy of it. It must be called with the lock held.
+func (p *Cache) handOver(cv *CacheValue) {
+	if p.Codec == nil && p.CloneFunc == nil {
+		cv.ref = nil
+	}
+}
+
+// untrack drops the reference of the cache to the value of cv, when it is
+// removed or replaced
+func (p *Cache) untrack(cv *CacheValue) {
+	if cv.ref != nil {
+		p.unref(cv.ref)
+		cv.ref = nil
+	}
+}
+
+// unref drops a reference to a value, disposing of it with the last
+func (p *Cache) unref(ref *valueRef) {
+	if atomic.AddInt32(&ref.refs, -1) == 0 && p.Disposer != nil {
+		p.Disposer(ref.value)
+	}
+}
@@ -0,0 +1,60 @@
+package expiringcache
+
+import "testing"
+
+func TestDisposer(t *testing.T) {
+	disposed := make(map[interface{}]int)
+	cache := Cache{Duration: 60,
+		Disposer: func(value interface{}) { disposed[value]++ }}
+	cache.Init()
+
+	cache.Put("a", "a1")
+	cache.Put("b", "b1")
+
+	h, ok := cache.Acquire("a")
+	if !ok || h.Value() != "a1" {
+		t.Fatalf("Acquire returned %v, %v", h, ok)
+	}
+	if _, ok := cache.Acquire("missing"); ok {
+		t.Errorf("Acquire found a missing key")
+	}
+
+	cache.Put("a", "a2")
+	cache.Del("b")
+	if disposed["a1"] != 0 || disposed["b1"] != 1 {
+		t.Errorf("Unexpected disposals %v", disposed)
+	}
+
+	h.Release()
+	h.Release()
+	if disposed["a1"] != 1 {
+		t.Errorf("Value disposed of %d times after release", disposed["a1"])
+	}
+
+	cache.Close()
+	if disposed["a2"] != 1 || len(disposed) != 3 {
+		t.Errorf("Unexpected disposals after Close %v", disposed)
+	}
+}
+
+func TestDisposerSwap(t *testing.T) {
+	disposed := make(map[interface{}]int)
+	cache := Cache{Duration: 60,
+		Disposer: func(value interface{}) { disposed[value]++ }}
+	cache.Init()
+
+	cache.Put("a", "a1")
+	h, _ := cache.Acquire("a")
+
+	old, ok, err := cache.Swap("a", "a2")
+	if old != "a1" || !ok || err != nil {
+		t.Fatalf("Swap returned %v, %v, %v", old, ok, err)
+	}
+
+	// the caller owns the returned value, even once handles are released
+	h.Release()
+	cache.Del("a")
+	if disposed["a1"] != 0 || disposed["a2"] != 1 {
+		t.Errorf("Unexpected disposals %v", disposed)
+	}
+}
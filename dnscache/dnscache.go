@@ -0,0 +1,152 @@
+// Package dnscache caches DNS lookups in an expiringcache.Cache, including
+// negative caching of names that don't exist.
+//
+// Answers are cached for the TTL of their records when the Lookuper
+// reports it, see TTLLookuper. The standard library resolver doesn't, so
+// its answers are cached for a fixed TTL instead.
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"github.com/deep-compute/expiringcache"
+	"net"
+	"time"
+)
+
+// DefaultTTL is the number of seconds answers are cached for when neither
+// the records nor Resolver.TTL give one
+const DefaultTTL = 60
+
+// Lookuper is the subset of *net.Resolver used by Resolver
+type Lookuper interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto,
+		name string) (string, []*net.SRV, error)
+}
+
+// TTLLookuper is a Lookuper that also returns the TTL of the records it
+// found, e.g. one built on a DNS client library. Resolver caches its
+// answers for that TTL, and not at all if it is 0.
+type TTLLookuper interface {
+	Lookuper
+	LookupHostTTL(ctx context.Context, host string) ([]string,
+		time.Duration, error)
+	LookupSRVTTL(ctx context.Context, service, proto,
+		name string) (string, []*net.SRV, time.Duration, error)
+}
+
+// Resolver answers lookups from Cache, falling back to Lookuper
+type Resolver struct {
+	Cache *expiringcache.Cache
+
+	// Lookuper resolves names missing from the cache. Defaults to
+	// net.DefaultResolver.
+	Lookuper Lookuper
+
+	// TTL is the number of seconds to cache answers for when the Lookuper
+	// doesn't give the TTL of their records. Defaults to DefaultTTL.
+	TTL         int
+	NegativeTTL int // seconds to cache "no such host" errors for, 0 to not
+}
+
+type srvAnswer struct {
+	cname string
+	addrs []*net.SRV
+}
+
+// negative is cached for names that don't exist
+type negative struct {
+	err error
+}
+
+func (p *Resolver) lookuper() Lookuper {
+	if p.Lookuper != nil {
+		return p.Lookuper
+	}
+	return net.DefaultResolver
+}
+
+// LookupHost is like net.Resolver.LookupHost. The returned slice is shared
+// with the cache and must not be modified.
+func (p *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	v, err := p.lookup(ctx, "host:"+host, func() (interface{}, int, error) {
+		if l, ok := p.lookuper().(TTLLookuper); ok {
+			addrs, ttl, err := l.LookupHostTTL(ctx, host)
+			return addrs, seconds(ttl), err
+		}
+		addrs, err := p.lookuper().LookupHost(ctx, host)
+		return addrs, p.ttl(), err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]string), nil
+}
+
+// LookupSRV is like net.Resolver.LookupSRV. The returned slice is shared
+// with the cache and must not be modified.
+func (p *Resolver) LookupSRV(ctx context.Context, service, proto,
+	name string) (string, []*net.SRV, error) {
+	key := "srv:" + service + ":" + proto + ":" + name
+	v, err := p.lookup(ctx, key, func() (interface{}, int, error) {
+		if l, ok := p.lookuper().(TTLLookuper); ok {
+			cname, addrs, ttl, err := l.LookupSRVTTL(ctx, service, proto,
+				name)
+			return srvAnswer{cname: cname, addrs: addrs}, seconds(ttl), err
+		}
+		cname, addrs, err := p.lookuper().LookupSRV(ctx, service, proto, name)
+		return srvAnswer{cname: cname, addrs: addrs}, p.ttl(), err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	a := v.(srvAnswer)
+	return a.cname, a.addrs, nil
+}
+
+// lookup returns the answer cached under key, or calls fn for it along with
+// the number of seconds to cache it for
+func (p *Resolver) lookup(ctx context.Context, key string,
+	fn func() (interface{}, int, error)) (interface{}, error) {
+	// Fetch rather than Get so answers past their TTL aren't served even
+	// if they haven't been swept yet
+	v, err := p.Cache.Fetch(key)
+	if err == nil {
+		if n, ok := v.(negative); ok {
+			return nil, n.err
+		}
+		return v, nil
+	}
+
+	v, ttl, err := fn()
+	if err == nil {
+		if ttl > 0 {
+			p.Cache.PutWithExpiry(key, v, ttl)
+		}
+		return v, nil
+	}
+
+	var dnsErr *net.DNSError
+	if p.NegativeTTL > 0 && errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		p.Cache.PutWithExpiry(key, negative{err: err}, p.NegativeTTL)
+	}
+
+	return nil, err
+}
+
+// ttl returns the number of seconds to cache answers without a TTL of
+// their own for
+func (p *Resolver) ttl() int {
+	if p.TTL > 0 {
+		return p.TTL
+	}
+	return DefaultTTL
+}
+
+// seconds returns the record TTL d in seconds, rounding up so short TTLs
+// still cache the answer
+func seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
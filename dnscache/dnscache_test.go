@@ -0,0 +1,112 @@
+package dnscache
+
+import (
+	"context"
+	"github.com/deep-compute/expiringcache"
+	"net"
+	"testing"
+	"time"
+)
+
+type fakeLookuper struct{ lookups int }
+
+func (p *fakeLookuper) LookupHost(ctx context.Context, host string) ([]string, error) {
+	p.lookups++
+	if host == "missing.example" {
+		return nil, &net.DNSError{Err: "no such host", Name: host,
+			IsNotFound: true}
+	}
+	return []string{"192.0.2.1"}, nil
+}
+
+func (p *fakeLookuper) LookupSRV(ctx context.Context, service, proto,
+	name string) (string, []*net.SRV, error) {
+	p.lookups++
+	return name, []*net.SRV{{Target: "a.example", Port: 80}}, nil
+}
+
+func TestResolver(t *testing.T) {
+	cache := expiringcache.Cache{Duration: 60}
+	cache.Init()
+
+	l := &fakeLookuper{}
+	r := &Resolver{Cache: &cache, Lookuper: l, TTL: 60, NegativeTTL: 10}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if addrs, err := r.LookupHost(ctx, "a.example"); err != nil ||
+			addrs[0] != "192.0.2.1" {
+			t.Errorf("LookupHost returned %v, %v", addrs, err)
+		}
+
+		if _, err := r.LookupHost(ctx, "missing.example"); err == nil {
+			t.Errorf("LookupHost of missing name succeeded")
+		}
+
+		if _, addrs, err := r.LookupSRV(ctx, "http", "tcp", "example"); err != nil ||
+			addrs[0].Port != 80 {
+			t.Errorf("LookupSRV returned %v, %v", addrs, err)
+		}
+	}
+
+	if l.lookups != 3 {
+		t.Errorf("%d lookups made, expected 3", l.lookups)
+	}
+}
+
+type ttlLookuper struct {
+	fakeLookuper
+	ttl time.Duration
+}
+
+func (p *ttlLookuper) LookupHostTTL(ctx context.Context, host string) ([]string,
+	time.Duration, error) {
+	addrs, err := p.LookupHost(ctx, host)
+	return addrs, p.ttl, err
+}
+
+func (p *ttlLookuper) LookupSRVTTL(ctx context.Context, service, proto,
+	name string) (string, []*net.SRV, time.Duration, error) {
+	cname, addrs, err := p.LookupSRV(ctx, service, proto, name)
+	return cname, addrs, p.ttl, err
+}
+
+func TestResolverTTL(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := expiringcache.Cache{Clock: func() time.Time { return now }}
+	cache.Init()
+	ctx := context.Background()
+
+	// the cache is never swept, expired answers must still be looked up
+	// again
+	l := &fakeLookuper{}
+	r := &Resolver{Cache: &cache, Lookuper: l}
+	r.LookupHost(ctx, "a.example")
+	now = now.Add((DefaultTTL - 1) * time.Second)
+	r.LookupHost(ctx, "a.example")
+	now = now.Add(2 * time.Second)
+	r.LookupHost(ctx, "a.example")
+	if l.lookups != 2 {
+		t.Errorf("%d lookups made with the default TTL, expected 2",
+			l.lookups)
+	}
+
+	tl := &ttlLookuper{ttl: 1500 * time.Millisecond}
+	r = &Resolver{Cache: &cache, Lookuper: tl, TTL: 60}
+	r.LookupSRV(ctx, "http", "tcp", "example")
+	now = now.Add(time.Second)
+	r.LookupSRV(ctx, "http", "tcp", "example")
+	now = now.Add(time.Second)
+	r.LookupSRV(ctx, "http", "tcp", "example")
+	if tl.lookups != 2 {
+		t.Errorf("%d lookups made with a record TTL of 1.5s, expected 2",
+			tl.lookups)
+	}
+
+	tl.ttl = 0
+	r.LookupHost(ctx, "b.example")
+	r.LookupHost(ctx, "b.example")
+	if tl.lookups != 4 {
+		t.Errorf("Answer with a record TTL of 0 cached")
+	}
+}
@@ -0,0 +1,87 @@
+package expiringcache
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Format of the output written by Dump
+type Format int
+
+const (
+	FormatJSON Format = iota // one JSON object per line
+	FormatCSV                // key,ttl,value with a header row
+)
+
+// DumpRecord is an entry as written by Dump. TTL is the number of seconds
+// left before the key expires, or -1 if it never expires. Origin is set
+// for keys written with WithOrigin and is left out of FormatCSV.
+type DumpRecord struct {
+	Key    string      `json:"key"`
+	TTL    int64       `json:"ttl"`
+	Value  interface{} `json:"value"`
+	Origin string      `json:"origin,omitempty"`
+}
+
+// Dump writes every entry of the cache to w in the given format, for
+// offline analysis of what is occupying the cache. Values are encoded with
+// encoding/json (also within the CSV value column).
+func (p *Cache) Dump(w io.Writer, format Format) error {
+	records := p.dumpRecords()
+
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"key", "ttl", "value"})
+		for _, r := range records {
+			value, err := json.Marshal(r.Value)
+			if err != nil {
+				return err
+			}
+
+			cw.Write([]string{r.Key, strconv.FormatInt(r.TTL, 10),
+				string(value)})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	return fmt.Errorf("expiringcache: unknown dump format %d", format)
+}
+
+// dumpRecords copies the entries so they can be written without holding
+// the lock
+func (p *Cache) dumpRecords() []DumpRecord {
+	p.Lock()
+	defer p.Unlock()
+
+	ts := p.now()
+	records := make([]DumpRecord, 0, p.data.Len())
+	for i := 0; i < p.data.Len(); i++ {
+		cv := p.data.At(i).(*CacheValue)
+
+		ttl := cv.ExpireAt - ts
+		if cv.ExpireAt == 0 {
+			ttl = -1
+		} else if ttl < 0 {
+			ttl = 0
+		}
+
+		records = append(records, DumpRecord{Key: cv.Key, TTL: ttl,
+			Value: p.clone(cv.Value), Origin: cv.Metadata[OriginKey]})
+	}
+
+	return records
+}
@@ -0,0 +1,37 @@
+package expiringcache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.PutWithDeadline("b", []string{"x"}, time.Now().Add(-10*time.Second))
+
+	var buf bytes.Buffer
+	if err := cache.Dump(&buf, FormatJSON); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+
+	expected := `{"key":"a","ttl":60,"value":1}
+{"key":"b","ttl":0,"value":["x"]}
+`
+	if buf.String() != expected {
+		t.Errorf("Unexpected JSON dump:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := cache.Dump(&buf, FormatCSV); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+
+	expected = "key,ttl,value\na,60,1\nb,0,\"[\"\"x\"\"]\"\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected CSV dump:\n%s", buf.String())
+	}
+}
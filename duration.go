@@ -0,0 +1,31 @@
+package expiringcache
+
+// DurationPolicy decides what happens to durations outside MinDuration and
+// MaxDuration
+type DurationPolicy int
+
+const (
+	ClampDuration  DurationPolicy = iota // the nearest bound is used
+	RejectDuration                       // Set returns ErrDuration
+)
+
+// boundExpiry applies MinDuration and MaxDuration to a key stored at ts
+// until expireAt, 0 meaning never
+func (p *Cache) boundExpiry(ts, expireAt int64) (int64, error) {
+	d := expireAt - ts
+
+	var bound int64
+	switch {
+	case p.MaxDuration > 0 && (expireAt == 0 || d > int64(p.MaxDuration)):
+		bound = ts + int64(p.MaxDuration)
+	case p.MinDuration > 0 && expireAt != 0 && d < int64(p.MinDuration):
+		bound = ts + int64(p.MinDuration)
+	default:
+		return expireAt, nil
+	}
+
+	if p.DurationPolicy == RejectDuration {
+		return 0, ErrDuration
+	}
+	return bound, nil
+}
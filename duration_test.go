@@ -0,0 +1,37 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationBounds(t *testing.T) {
+	clock := func() time.Time { return time.Unix(1000, 0) }
+	cache := Cache{Duration: 60, MinDuration: 10, MaxDuration: 3600,
+		Clock: clock}
+	cache.Init()
+
+	cache.PutWithExpiry("short", 1, 1)
+	cache.PutWithExpiry("long", 1, 365*24*3600)
+	cache.PutWithExpiry("forever", 1, 0)
+
+	for key, want := range map[string]time.Duration{"short": 10 * time.Second,
+		"long": time.Hour, "forever": time.Hour} {
+		if d, _ := cache.TTL(key); d != want {
+			t.Errorf("TTL of %q is %v, expected %v", key, d, want)
+		}
+	}
+
+	strict := Cache{Duration: 60, MaxDuration: 3600,
+		DurationPolicy: RejectDuration}
+	strict.Init()
+
+	if err := strict.SetWithExpiry("a", 1, 7200); err != ErrDuration ||
+		strict.Exists("a") {
+		t.Errorf("Duration over MaxDuration returned %v", err)
+	}
+
+	if err := strict.Set("a", 1); err != nil {
+		t.Errorf("Duration within bounds returned %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package expiringcache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by the cache. They are returned either as is or wrapped
+// with details using %w, so check for them with errors.Is.
+var (
+	// The key is not in the cache
+	ErrNotFound = errors.New("expiringcache: key not found")
+	// The key is in the cache but has expired and not been removed yet.
+	// Treat it like ErrNotFound.
+	ErrExpired = errors.New("expiringcache: key expired")
+	// The key was refused by MaxKeyLength, RejectEmptyKeys or KeyRunes,
+	// wrapped with the reason
+	ErrInvalidKey = errors.New("expiringcache: invalid key")
+	// The value is over MaxValueBytes
+	ErrTooLarge = errors.New("expiringcache: entry too large")
+	// A new key was refused with RejectOnFull
+	ErrCacheFull = errors.New("expiringcache: cache is full")
+	// The duration is out of MinDuration and MaxDuration with
+	// RejectDuration
+	ErrDuration = errors.New("expiringcache: duration out of bounds")
+	// The key holds a value other than a map of fields, from HSet
+	ErrWrongType = errors.New("expiringcache: value is not a map")
+	// The cache was used after Close
+	ErrClosed = errors.New("expiringcache: cache is closed")
+	// The cache is locked by another goroutine, from TryGet and TryPut
+	ErrLocked = errors.New("expiringcache: cache is locked")
+	// The configuration is invalid, wrapped with the problem found
+	ErrInvalidConfig = errors.New("expiringcache: invalid configuration")
+	// A snapshot or replication stream is truncated or corrupt, wrapped
+	// with where the damage was found
+	ErrSnapshot = errors.New("expiringcache: corrupt snapshot")
+	// Encrypted data was modified, truncated or encrypted with a
+	// different key
+	ErrDecrypt = errors.New("expiringcache: cannot decrypt data")
+	// Replicate lost changes because the follower fell too far behind.
+	// The follower should reconnect, which starts over with a full copy.
+	ErrReplicationLag = errors.New("expiringcache: replication fell behind")
+	// Loader panicked while loading a key for another caller, which the
+	// panic is passed on to
+	ErrLoaderPanic = errors.New("expiringcache: Loader panicked")
+)
+
+func configError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidConfig, fmt.Sprintf(format, args...))
+}
+
+// Validate checks the configuration of the cache, returning an error
+// wrapping ErrInvalidConfig describing the first problem found.
+func (p *Cache) Validate() error {
+	switch {
+	case p.Max < 0:
+		return configError("Max is negative")
+	case p.Max > 0 && p.HighWatermark == 0 && p.NEvictions <= 0:
+		return configError("NEvictions must be positive when Max is set")
+	case p.HighWatermark < 0 || p.LowWatermark < 0:
+		return configError("watermarks are negative")
+	case p.HighWatermark > 0 && p.LowWatermark >= p.HighWatermark:
+		return configError("LowWatermark must be below HighWatermark")
+	case p.NSamples < 0:
+		return configError("NSamples is negative")
+	case p.TTLJitter < 0 || p.TTLJitter >= 1:
+		return configError("TTLJitter must be in [0, 1)")
+	case p.RefreshAhead < 0 || p.RefreshAhead > 1:
+		return configError("RefreshAhead must be in [0, 1]")
+	case p.RefreshAhead > 0 && p.Loader == nil:
+		return configError("RefreshAhead requires a Loader")
+	case p.MinDuration < 0 || p.MaxDuration < 0 || p.AdaptiveHits < 0:
+		return configError("duration settings are negative")
+	case p.MaxDuration > 0 && p.MinDuration > p.MaxDuration:
+		return configError("MinDuration is above MaxDuration")
+	case p.SLRUProtectedRatio < 0 || p.SLRUProtectedRatio >= 1:
+		return configError("SLRUProtectedRatio must be in [0, 1)")
+	case !positiveQuotas(p.Quotas):
+		return configError("Quotas must be positive")
+	case p.MaxCost < 0:
+		return configError("MaxCost is negative")
+	case p.MaxValueBytes < 0:
+		return configError("MaxValueBytes is negative")
+	case p.MaxKeyLength < 0:
+		return configError("MaxKeyLength is negative")
+	case p.SaturatedTimeout < 0:
+		return configError("SaturatedTimeout is negative")
+	case p.ExpiryGranularity < 0:
+		return configError("ExpiryGranularity is negative")
+	case p.MissFilterSize < 0:
+		return configError("MissFilterSize is negative")
+	case p.SweepBatchSize < 0 || p.ActiveExpirySamples < 0:
+		return configError("sweep settings are negative")
+	}
+
+	return nil
+}
+
+// Fetch is like Get but returns ErrNotFound if key is not in the cache and
+// ErrExpired if it has expired, where Get would still return its value
+// until it is removed
+func (p *Cache) Fetch(key string) (interface{}, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return nil, ErrClosed
+	}
+
+	cv := p.access(key)
+	if cv == nil {
+		return nil, ErrNotFound
+	}
+	if cv.expired(p.now()) {
+		return nil, ErrExpired
+	}
+
+	return p.clone(cv.Value), nil
+}
+
+// Remove is like Del but returns ErrNotFound if key is not in the cache
+func (p *Cache) Remove(key string) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	v := p.find(p.canonicalKey(key))
+	if v == nil {
+		return ErrNotFound
+	}
+
+	p.remove(v, EventDelete)
+	p.bury(v.Key)
+	return nil
+}
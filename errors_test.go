@@ -0,0 +1,76 @@
+package expiringcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestErrorReturningAPI(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", nil)
+	if v, err := cache.Fetch("a"); v != nil || err != nil {
+		t.Errorf("Fetch of nil value returned %v, %v", v, err)
+	}
+
+	if _, err := cache.Fetch("b"); err != ErrNotFound {
+		t.Errorf("Fetch of missing key returned %v", err)
+	}
+
+	if err := cache.Remove("a"); err != nil {
+		t.Errorf("Remove of existing key returned %v", err)
+	}
+
+	if err := cache.Remove("a"); err != ErrNotFound {
+		t.Errorf("Remove of missing key returned %v", err)
+	}
+
+	cache.PutWithDeadline("c", 1, time.Now().Add(-time.Second))
+	if _, err := cache.Fetch("c"); err != ErrExpired {
+		t.Errorf("Fetch of expired key returned %v", err)
+	}
+}
+
+func TestErrClosed(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+	cache.Put("a", 1)
+	cache.Close()
+
+	if err := cache.Set("a", 1); err != ErrClosed {
+		t.Errorf("Put after Close returned %v", err)
+	}
+	if _, err := cache.Fetch("a"); err != ErrClosed {
+		t.Errorf("Fetch after Close returned %v", err)
+	}
+	if err := cache.Remove("a"); err != ErrClosed {
+		t.Errorf("Remove after Close returned %v", err)
+	}
+	if cache.Count() != 0 {
+		t.Errorf("Closed cache holds %d keys", cache.Count())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Cache{Duration: 1, Max: 1, NEvictions: 10}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Valid configuration rejected: %v", err)
+	}
+
+	invalid := []*Cache{
+		{Max: -1},
+		{Max: 10},
+		{TTLJitter: 1.5},
+		{HighWatermark: 10, LowWatermark: 10},
+		{SLRUProtectedRatio: 1},
+		{RefreshAhead: 0.8},
+	}
+
+	for _, c := range invalid {
+		if err := c.Validate(); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Invalid configuration accepted")
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package expiringcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EventType is what happened to a key, which for removals is the reason it
+// was removed
+type EventType int
+
+const (
+	EventPut    EventType = iota // a new key was added
+	EventUpdate                  // an existing key's value was replaced
+	EventDelete                  // a key was removed with Del or a Pop
+	EventExpire                  // a key was removed after expiring
+	EventEvict                   // a key was evicted to make room
+	EventClear                   // a key was removed by Close
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventPut:
+		return "put"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	case EventExpire:
+		return "expire"
+	case EventEvict:
+		return "evict"
+	case EventClear:
+		return "clear"
+	}
+	return "unknown"
+}
+
+// Event describes a change made to the cache
+type Event struct {
+	Type EventType
+	Key  string
+	// TTL the key had left when the event happened, 0 if it had expired
+	// and NoExpiry if it never expires. For evicted keys it tells how much
+	// of their lifetime was cut short.
+	TTL time.Duration
+	// Origin of the last write of the key, see WithOrigin
+	Origin string
+}
+
+// event returns the event of type typ for cv
+func (p *Cache) event(typ EventType, cv *CacheValue) Event {
+	ttl := NoExpiry
+	if cv.ExpireAt != 0 {
+		ttl = time.Duration(max(cv.ExpireAt-p.now(), 0)) * time.Second
+	}
+	return Event{Type: typ, Key: cv.Key, TTL: ttl,
+		Origin: cv.Metadata[OriginKey]}
+}
+
+type subscription struct {
+	c      chan Event
+	filter func(Event) bool // events not matching are not delivered
+	once   sync.Once
+
+	// disconnect when the buffer is full, as with DisconnectSlowSubscribers
+	disconnect bool
+}
+
+const defaultEventBufferSize = 128
+
+// Subscribe returns a channel on which changes to the cache are published
+// and a function that ends the subscription and closes the channel.
+//
+// Events are buffered up to EventBufferSize per subscriber. Once the buffer
+// is full further events are dropped, or the subscriber is disconnected
+// (its channel closed) if DisconnectSlowSubscribers is set.
+func (p *Cache) Subscribe() (<-chan Event, func()) {
+	n := p.EventBufferSize
+	if n == 0 {
+		n = defaultEventBufferSize
+	}
+
+	p.Lock()
+	s := p.subscribe(n, nil)
+	p.Unlock()
+
+	return s.c, p.cancelFunc(s)
+}
+
+// WaitFor returns the value of key, blocking until it is added to the
+// cache or ctx is done, in which case the context's error is returned.
+func (p *Cache) WaitFor(ctx context.Context, key string) (interface{}, error) {
+	key = p.canonicalKey(key)
+	for {
+		p.Lock()
+		if v := p.find(key); v != nil {
+			p.Unlock()
+			return p.clone(v.Value), nil
+		}
+
+		// subscribe while still holding the lock so that a Put
+		// cannot slip in between the lookup and the subscription
+		s := p.subscribe(1, func(e Event) bool {
+			return e.Key == key &&
+				(e.Type == EventPut || e.Type == EventUpdate)
+		})
+		p.Unlock()
+
+		select {
+		case <-s.c:
+			p.cancelFunc(s)()
+		case <-ctx.Done():
+			p.cancelFunc(s)()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// subscribe must be called with the lock held
+func (p *Cache) subscribe(n int, filter func(Event) bool) *subscription {
+	s := &subscription{c: make(chan Event, n), filter: filter}
+	if p.subs == nil {
+		p.subs = make(map[*subscription]struct{})
+	}
+	p.subs[s] = struct{}{}
+	return s
+}
+
+func (p *Cache) cancelFunc(s *subscription) func() {
+	return func() {
+		p.Lock()
+		p.unsubscribe(s)
+		p.Unlock()
+	}
+}
+
+// unsubscribe must be called with the lock held
+func (p *Cache) unsubscribe(s *subscription) {
+	delete(p.subs, s)
+	s.once.Do(func() { close(s.c) })
+}
+
+// publish must be called with the lock held
+func (p *Cache) publish(e Event) {
+	for s := range p.subs {
+		if s.filter != nil && !s.filter(e) {
+			continue
+		}
+
+		select {
+		case s.c <- e:
+		default:
+			// filtered subscriptions are internal waiters which only
+			// need a single event, so never disconnect those
+			if (p.DisconnectSlowSubscribers || s.disconnect) &&
+				s.filter == nil {
+				p.unsubscribe(s)
+			}
+		}
+	}
+}
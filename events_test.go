@@ -0,0 +1,85 @@
+package expiringcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	ts := time.Unix(1000, 0)
+	cache := Cache{Duration: 60, Max: 1, NEvictions: 1,
+		Clock: func() time.Time { return ts }}
+	cache.Init()
+
+	events, cancel := cache.Subscribe()
+
+	cache.Put("a", 1)
+	cache.Put("a", 2)
+	ts = ts.Add(10 * time.Second)
+	cache.Put("b", 3)
+	cache.Del("b")
+	cache.Del("b")
+	cache.PutWithExpiry("c", 4, 0)
+
+	expected := []Event{
+		{Type: EventPut, Key: "a", TTL: time.Minute},
+		{Type: EventUpdate, Key: "a", TTL: time.Minute},
+		{Type: EventEvict, Key: "a", TTL: 50 * time.Second},
+		{Type: EventPut, Key: "b", TTL: time.Minute},
+		{Type: EventDelete, Key: "b", TTL: time.Minute},
+		{Type: EventPut, Key: "c", TTL: NoExpiry},
+	}
+
+	for _, want := range expected {
+		if got := <-events; got != want {
+			t.Errorf("Got event %v %q %v, expected %v %q %v",
+				got.Type, got.Key, got.TTL, want.Type, want.Key, want.TTL)
+		}
+	}
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Errorf("Channel not closed after cancelling subscription")
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	cache := Cache{Duration: 60, EventBufferSize: 1,
+		DisconnectSlowSubscribers: true}
+	cache.Init()
+
+	events, cancel := cache.Subscribe()
+	defer cancel()
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+
+	<-events
+	if _, ok := <-events; ok {
+		t.Errorf("Slow subscriber was not disconnected")
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cache.Put("a", 1)
+	}()
+
+	v, err := cache.WaitFor(context.Background(), "a")
+	if err != nil || v.(int) != 1 {
+		t.Errorf("WaitFor returned %v, %v; expected 1", v, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		50*time.Millisecond)
+	defer cancel()
+
+	if _, err := cache.WaitFor(ctx, "b"); err != context.DeadlineExceeded {
+		t.Errorf("WaitFor returned %v on timeout", err)
+	}
+}
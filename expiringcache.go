@@ -2,16 +2,56 @@
 package expiringcache
 
 import (
+	"context"
 	"github.com/prashanthellina/go-avltree"
+	"io"
+	"math"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type CacheValue struct {
 	Key      string
 	Value    interface{}
-	ExpireAt int64
+	ExpireAt int64 // 0 if the key never expires
+	Priority int   // lower priority keys are evicted first
+
+	CreatedAt      int64 // when the key was first added
+	LastAccessedAt int64 // when the key was last fetched with Get
+	HitCount       int64 // number of times the key was fetched with Get
+
+	Version uint64 // changes every time the value is written
+	StaleAt int64  // when the key becomes stale (see PutWithSoftExpiry)
+
+	Metadata map[string]string // annotations set with PutWithMetadata
+
+	ttl        int64             // time from the last write to ExpireAt
+	duration   int64             // ttl before jitter and rounding
+	softTTL    int64             // duration after which the key goes stale
+	refreshing bool              // a refresh-ahead reload is in progress
+	cost       int64             // what the value counts against MaxCost
+	writeHits  int64             // HitCount when the key was last written
+	indexed    map[string]string // value of the key in each index
+	timer      *time.Timer       // removes the key with ExactExpiry
+	bucket     int64             // expiry bucket holding the key, if any
+	ref        *valueRef         // references to Value with a Disposer
+	deps       []string          // keys whose removal removes this one
+}
+
+// expired reports whether the key should be gone at time ts
+func (p *CacheValue) expired(ts int64) bool {
+	return p.ExpireAt != 0 && p.ExpireAt <= ts
+}
+
+// expiry returns ExpireAt for ordering keys by expiry, with keys that never
+// expire coming last
+func (p *CacheValue) expiry() int64 {
+	if p.ExpireAt == 0 {
+		return math.MaxInt64
+	}
+	return p.ExpireAt
 }
 
 func (p CacheValue) Compare(b avltree.Interface) int {
@@ -26,98 +66,922 @@ func (p CacheValue) Compare(b avltree.Interface) int {
 	return 0
 }
 
-// Cache that supports expiry of keys
+// Cache that supports expiry of keys. It is safe for concurrent use, but
+// its configuration fields must not be changed after Init, other than with
+// Reconfigure.
 type Cache struct {
-	Duration int // Number of seconds to keep key
+	Duration int // Number of seconds to keep key, 0 to keep it until removed
 	// in cache
 	Max        int // max number of keys in cache
 	NEvictions int // number of evictions to perform
 	// when keys reaches max limit
 	NSamples int // number of keys to consider for
 
+	// If HighWatermark is set, evictions start once the cache holds that
+	// many keys and continue until only LowWatermark keys are left, instead
+	// of removing NEvictions keys at Max. This avoids evicting on every Put
+	// once the cache is at its limit.
+	HighWatermark int
+	LowWatermark  int
+	// If MaxCost is set, keys are also evicted when adding a key would take
+	// the total cost of all entries over it. The cost of an entry is given
+	// by CostFunc, e.g. the size of the value, or 1 if it isn't set. The
+	// limit is enforced when keys are added, not when they are updated.
+	MaxCost  int64
+	CostFunc func(key string, value interface{}) int64
+	// If set, values are passed through CloneFunc before being returned by
+	// Get and the other reads, so callers get their own copy and can't
+	// modify the value seen by others. It must return a deep copy.
+	CloneFunc func(value interface{}) interface{}
+	// If set, values are stored encoded with Codec and decoded on every
+	// read instead, which isolates readers from each other whatever the
+	// value, at the cost of CPU. Values that fail to decode are read as nil.
+	Codec Codec
+	// If set, GetCtx, PutCtx and the Loader calls they make are traced
+	Tracer Tracer
+	// Returns the current time, used for all expiry decisions. Defaults to
+	// time.Now; replace it to replay or simulate traffic.
+	Clock func() time.Time
+	// If set, notable events are logged to Logger, e.g. a *slog.Logger.
+	// Summaries of the evictions are only logged by the periodic eviction.
+	Logger Logger
+	// If AdaptiveTTL is set, keys written again with Put, while still in
+	// the cache, are stored for twice their previous duration if they were
+	// fetched at least AdaptiveHits times (default 2) since the last write
+	// and for half of it if they weren't fetched at all, within
+	// MinDuration and MaxDuration. This replaces Duration and TTLFunc for
+	// such keys.
+	AdaptiveTTL  bool
+	AdaptiveHits int
+	// If set, keys are stored for at least MinDuration and at most
+	// MaxDuration seconds, e.g. so a caller can't store a value for a year
+	// by mistake. Keys that never expire count as over MaxDuration.
+	// DurationPolicy decides whether other durations are clamped or make
+	// Put return ErrDuration.
+	MinDuration    int
+	MaxDuration    int
+	DurationPolicy DurationPolicy
+	// What Put does with new keys when the cache is full. Updates of keys
+	// already in the cache are always allowed.
+	WritePolicy WritePolicy
+	// Keys with at least this priority, if positive, are not evicted to
+	// make room for others. Saturated decides what happens to new keys
+	// once only such keys are left and the cache is full. Put,
+	// PutWithExpiry and PutWithPriority wait up to SaturatedTimeout with
+	// BlockWhenSaturated, or for as long as it takes if it is 0, while
+	// other writes return ErrCacheFull.
+	ProtectedPriority int
+	Saturated         SaturatedPolicy
+	SaturatedTimeout  time.Duration
+
 	// Interval in seconds between which evictions are done periodically
 	// By default this is 0 i.e. disabled
 	PeriodicEvictionInterval uint64
+	// Number of keys checked by the periodic eviction before briefly
+	// releasing the lock. Defaults to 1024.
+	SweepBatchSize int
+	// If set, the periodic eviction checks this many random keys per run
+	// instead of all of them, repeating while over 25% of them expired.
+	ActiveExpirySamples int
+	// If set, the periodic eviction is run by Sweeper, shared with other
+	// caches, instead of by a goroutine of the cache's own
+	Sweeper *Sweeper
+
+	// Admitter, if set, is asked whether a new key may be added when the
+	// cache is full. Rejected keys are dropped instead of evicting others.
+	Admitter Admitter
+
+	// Loader fetches the current value of a key from the backing store.
+	// It is used by GetCtx on a miss and to reload keys in the background
+	// (see RefreshAhead).
+	Loader func(ctx context.Context, key string) (interface{}, error)
+	// BatchLoader fetches the values of several keys from the backing
+	// store in one round trip, for the misses of GetManyCtx. Keys it
+	// leaves out of the result are treated as not found. If it isn't set,
+	// GetManyCtx uses Loader for each miss instead.
+	BatchLoader func(ctx context.Context,
+		keys []string) (map[string]interface{}, error)
+	// Fraction of a key's duration after which a Get reloads it in the
+	// background using Loader, e.g. 0.8 refreshes keys fetched during the
+	// last 20% of their lifetime so hot keys never expire. 0 disables this.
+	RefreshAhead float64
+	// Seconds for which a key removed with Del or Remove keeps a
+	// tombstone. Values loaded with Loader meanwhile are returned but not
+	// stored, so a load already in flight when the key was deleted can't
+	// bring back a stale value. Put and the other writes are unaffected.
+	// 0 disables tombstones.
+	TombstoneDuration int
+
+	// If set, the bookkeeping of the cache (count, cost, indexes, expiry
+	// timers, dependencies) is checked against its entries after every
+	// write and removal, panicking with what doesn't match. This walks all
+	// entries each time, so it is meant for tests and debugging only.
+	CheckInvariants bool
+
+	// TTLFunc, if set, gives the duration to store a key for when none is
+	// passed explicitly, e.g. to honour expiry information in the value.
+	// Returning 0 falls back to Duration.
+	TTLFunc func(key string, value interface{}) time.Duration
+
+	// Randomizes each key's duration by up to this fraction in either
+	// direction (e.g. 0.1 for ±10%) so keys written together don't all
+	// expire together. 0 disables jitter.
+	TTLJitter float64
+
+	// Number of locks handed out by KeyLock. Defaults to 256.
+	KeyLockStripes int
+
+	// If set, every key passed to the cache is replaced by KeyTransform(key),
+	// e.g. strings.ToLower, so differently formatted keys find the same
+	// entry. Entries, events and dumps carry the transformed keys. It must
+	// give the same result when applied again to a transformed key. The
+	// shards of a ShardedCache are picked before it applies, so transform
+	// keys before passing them to a ShardedCache instead.
+	KeyTransform func(key string) string
+	// Keys written to the cache must be at most MaxKeyLength bytes long,
+	// be non-empty if RejectEmptyKeys is set and contain only runes
+	// accepted by KeyRunes, if set, e.g. so they can be sent over text
+	// protocols. Writes of other keys return an error wrapping
+	// ErrInvalidKey. Keys are checked after KeyTransform.
+	MaxKeyLength    int
+	RejectEmptyKeys bool
+	KeyRunes        func(r rune) bool
+
+	// If set, the keys in the cache are kept in a counting Bloom filter
+	// of this many counters, e.g. 10 per key expected, so Get, Lookup and
+	// Exists of keys that are certainly missing return without taking the
+	// lock. This pays off when most lookups miss.
+	MissFilterSize int
+
+	// Largest []byte or string value accepted by Put, 0 for no limit.
+	// Values of other types are not limited.
+	MaxValueBytes int
+	// What Put does with values larger than MaxValueBytes
+	OversizePolicy OversizePolicy
+
+	// Source of randomness for sampling evictions, PopRandom, TTLJitter
+	// and ProbabilisticAdmitter. Set it to a seeded source to reproduce
+	// those decisions.
+	RandSource rand.Source
+	// Evict the lowest priority, soonest expiring key by scanning all keys
+	// instead of sampling NSamples of them. Slower but deterministic.
+	EvictSoonest bool
+	// If set, each key that expires gets a timer removing it as soon as it
+	// expires, rather than waiting for the periodic eviction. Timers cost
+	// memory and a goroutine when they fire, so this suits small caches
+	// where removing keys on time matters more than scale. Timers run on
+	// real time, even if Clock is set.
+	ExactExpiry bool
+	// If more than 1, expiry times are rounded up to a multiple of this
+	// many seconds, so keys written around the same time expire together.
+	// With ExactExpiry they then share one timer per bucket, which makes
+	// it affordable for caches of many short-lived keys. Keys may outlive
+	// their duration, or MaxDuration, by up to a bucket.
+	ExpiryGranularity int
+	// If set, Disposer is called with each value once it has left the
+	// cache, by removal or by being replaced, and every Handle to it from
+	// Acquire is released, e.g. to unmap memory the value refers to. It is
+	// called exactly once per value, other than values returned by Swap,
+	// possibly with the lock held, so it must not use the cache.
+	Disposer func(value interface{})
+	// How keys to evict are chosen. Defaults to PolicySampled.
+	Policy Policy
+	// If set, chooses the keys to evict instead of Policy, e.g. NewLRU,
+	// NewLFU or a policy of one's own. It must not be shared between
+	// caches.
+	EvictionPolicy EvictionPolicy
+	// Maximum number of keys with each prefix, e.g. to keep one tenant of
+	// a shared cache from evicting the keys of others. A new key over the
+	// quota of its longest matching prefix evicts another key with that
+	// prefix, chosen as by PolicySampled, instead of a key of any prefix.
+	// Quotas should add up to no more than Max for this to hold under
+	// pressure.
+	Quotas map[string]int
+	// Secondary indexes by name, each giving the value a cached value is
+	// found by with GetByIndex, e.g. the user ID of a session. An empty
+	// result leaves the value out of the index. Values are indexed when
+	// they are written.
+	Indexes map[string]func(value interface{}) string
+	// Share of the cache for keys used more than once with PolicySLRU.
+	// Defaults to 0.8.
+	SLRUProtectedRatio float64
+
+	// Number of most frequently fetched keys to track for TopKeys, 0 to
+	// disable tracking
+	HotKeys int
+	// If set, the latencies of Get, Put and Loader are tracked for
+	// Latencies, at the cost of reading the clock for each call
+	TrackLatency bool
+
+	// Number of events buffered per subscriber (see Subscribe). Defaults
+	// to 128.
+	EventBufferSize int
+	// When a subscriber's buffer is full its events are dropped. Set this
+	// to disconnect such slow subscribers instead.
+	DisconnectSlowSubscribers bool
+
+	// If set, Close writes every entry to DumpOnClose as with Dump in
+	// FormatJSON before dropping them
+	DumpOnClose io.Writer
 	// performing an eviction
-	data *avltree.ObjectTree
+	data       *avltree.ObjectTree
+	keys       map[string]*CacheValue // the entries of data by key, see find
+	subs       map[*subscription]struct{}
+	keyLocks   []sync.Mutex
+	rnd        *rand.Rand
+	cost       int64
+	evicted    int // keys evicted since the last periodic eviction
+	policy     EvictionPolicy
+	tenants    []*tenant
+	indexes    map[string]*index
+	latency    *latencies
+	loads      map[string]*loadCall // Loader calls in progress by key
+	middleware []Middleware
+	tombstones map[string]int64 // until when deleted keys are tombstoned
+	goroutines int32            // running goroutines, see Diagnostics
+	filter     *missFilter
+	room       *sync.Cond              // signalled on removal with BlockWhenSaturated
+	buckets    map[int64]*expiryBucket // keys by rounded expiry
+	// keys depending on each key, see PutWithDeps
+	dependents map[string]map[string]struct{}
+	group      *Manager
+	hits       uint64 // keys found, read by Manager
+	size       int64  // number of keys, read by Count without the lock
+	version    uint64
+	hot        *hotKeys
+	stop       chan struct{}
+	stopOnce   sync.Once
+	closed     bool
+
+	interval  int64  // time between periodic evictions, read atomically
+	paused    uint32 // periodic eviction paused, read atomically
+	sweepWake chan struct{}
+	sweepOnce sync.Once
+
+	contended uint64 // times Lock had to wait for the lock
 	sync.Mutex
 }
 
+const defaultSweepBatchSize = 1024
+
+// Lock locks the cache, counting how often it had to wait for another
+// goroutine to release it (see Contention)
+func (p *Cache) Lock() {
+	if !p.Mutex.TryLock() {
+		atomic.AddUint64(&p.contended, 1)
+		p.Mutex.Lock()
+	}
+}
+
+// TryLock locks the cache if it is not held by another goroutine and
+// reports whether it did. Failures count as contention.
+func (p *Cache) TryLock() bool {
+	if !p.Mutex.TryLock() {
+		atomic.AddUint64(&p.contended, 1)
+		return false
+	}
+	return true
+}
+
+// TryGet is like Get but returns ErrLocked instead of waiting if another
+// goroutine holds the lock, e.g. a sweep, so latency-critical callers can
+// fall back to the backing store.
+func (p *Cache) TryGet(key string) (interface{}, error) {
+	if !p.TryLock() {
+		return nil, ErrLocked
+	}
+	defer p.Unlock()
+
+	v, _ := p.get(key)
+	return v, nil
+}
+
+// TryPut is like Put but returns ErrLocked instead of waiting if another
+// goroutine holds the lock
+func (p *Cache) TryPut(key string, value interface{}) error {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	if !p.TryLock() {
+		return ErrLocked
+	}
+	defer p.Unlock()
+
+	_, _, err = p.put(key, value, p.defaultOptions(key, value))
+	return err
+}
+
+// Contention returns the number of times the cache was locked while another
+// goroutine held it. A high count relative to the number of operations
+// suggests spreading keys over more shards (see ShardedCache).
+func (p *Cache) Contention() uint64 {
+	return atomic.LoadUint64(&p.contended)
+}
+
 func (p *Cache) Init() {
 	p.data = avltree.NewObjectTree(0)
-	if p.PeriodicEvictionInterval == 0 {
-		return
+	p.keys = make(map[string]*CacheValue)
+	p.stop = make(chan struct{})
+	p.initKeyLocks()
+	if p.RandSource != nil {
+		p.rnd = rand.New(p.RandSource)
+		if a, ok := p.Admitter.(*ProbabilisticAdmitter); ok {
+			a.float64 = p.float64
+		}
+	}
+	if p.HotKeys > 0 {
+		p.hot = newHotKeys(p.HotKeys)
 	}
+	p.policy = p.newPolicy()
+	p.initTenants()
+	p.initIndexes()
+	if p.MissFilterSize > 0 {
+		p.filter = newMissFilter(p.MissFilterSize)
+	}
+	if p.Saturated == BlockWhenSaturated {
+		p.room = sync.NewCond(&p.Mutex)
+	}
+	if p.TrackLatency {
+		p.latency = &latencies{}
+	}
+	p.sweepWake = make(chan struct{}, 1)
+	p.interval = int64(time.Duration(p.PeriodicEvictionInterval) * time.Second)
+	if p.interval > 0 {
+		p.startSweeps()
+	}
+}
 
-	go p.evictPeriodically()
+// startSweeps starts the periodic eviction, once
+func (p *Cache) startSweeps() {
+	p.sweepOnce.Do(func() {
+		if p.Sweeper != nil {
+			p.Sweeper.add(p)
+			return
+		}
+		p.background(p.evictPeriodically)
+	})
 }
 
 func (p *Cache) evictPeriodically() {
-	numSeconds := time.Duration(p.PeriodicEvictionInterval) * time.Second
-	var cv *CacheValue
 	for {
-		time.Sleep(numSeconds)
+		// with no interval, wait for one to be set
+		var timer *time.Timer
+		var tick <-chan time.Time
+		if d := p.sweepInterval(); d > 0 {
+			timer = time.NewTimer(d)
+			tick = timer.C
+		}
+
+		stopped := false
+		select {
+		case <-tick:
+			p.evictExpired()
+		case <-p.sweepWake:
+		case <-p.stop:
+			stopped = true
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+// sweepInterval returns the time between periodic evictions, 0 if they are
+// disabled
+func (p *Cache) sweepInterval() time.Duration {
+	return time.Duration(atomic.LoadInt64(&p.interval))
+}
+
+// PauseSweeper stops the periodic eviction until ResumeSweeper is called,
+// e.g. to back off background work during a traffic spike. Get and the
+// other reads don't check expiry, so expired keys are returned until they
+// are removed, which may then take longer.
+func (p *Cache) PauseSweeper() {
+	atomic.StoreUint32(&p.paused, 1)
+}
+
+// ResumeSweeper restarts the periodic eviction stopped by PauseSweeper
+func (p *Cache) ResumeSweeper() {
+	atomic.StoreUint32(&p.paused, 0)
+}
+
+// SetSweepInterval replaces PeriodicEvictionInterval while the cache is in
+// use, starting the periodic eviction if it wasn't running. d of 0 stops
+// it. The next eviction is d from now.
+func (p *Cache) SetSweepInterval(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	atomic.StoreInt64(&p.interval, int64(d))
+
+	if d > 0 {
+		p.startSweeps()
+	}
+	if p.Sweeper != nil {
+		p.Sweeper.add(p)
+		return
+	}
+
+	select {
+	case p.sweepWake <- struct{}{}:
+	default:
+	}
+}
+
+// evictExpired is one run of the periodic eviction, skipped while it is
+// paused
+func (p *Cache) evictExpired() {
+	if atomic.LoadUint32(&p.paused) != 0 {
+		return
+	}
+
+	p.purgeTombstones()
+
+	var expired int
+	if p.ActiveExpirySamples > 0 {
+		expired = p.expireSampled()
+	} else {
+		expired = p.sweep()
+	}
+	p.logEvictions(expired)
+}
+
+// maximum rounds of sampling done by one expireSampled call
+const maxActiveExpiryRounds = 16
+
+// expireSampled removes expired keys the way Redis does: it checks
+// ActiveExpirySamples random keys, removing the expired ones, and goes
+// again if more than a quarter of them had expired. The cost is bounded
+// regardless of cache size while still keeping the share of expired keys
+// low.
+func (p *Cache) expireSampled() int {
+	removed := 0
+	for round := 0; round < maxActiveExpiryRounds; round++ {
 		p.Lock()
-		now := time.Now().UTC().Unix()
-		to_remove := make([]*CacheValue, 0)
-		for v := range p.data.Iter() {
-			cv = v.(*CacheValue)
-			// if it is going to expire in the future, leave it
-			if cv.ExpireAt > now {
-				continue
+
+		ts := p.now()
+		expired := 0
+		n := p.ActiveExpirySamples
+		for i := 0; i < n && p.data.Len() > 0; i++ {
+			cv := p.data.At(p.intn(p.data.Len())).(*CacheValue)
+			if cv.expired(ts) {
+				p.remove(cv, EventExpire)
+				expired++
 			}
-			// it should expire now. add it to things to remove
-			to_remove = append(to_remove, cv)
 		}
 
-		for _, cv = range to_remove {
-			p.data.Remove(cv)
+		p.Unlock()
+
+		removed += expired
+		if expired*4 <= n {
+			break
 		}
+	}
+
+	return removed
+}
 
+// sweep removes all expired keys, holding the lock for at most
+// SweepBatchSize keys at a time so other operations can interleave.
+func (p *Cache) sweep() int {
+	batch := p.SweepBatchSize
+	if batch <= 0 {
+		batch = defaultSweepBatchSize
+	}
+
+	removed := 0
+	for i := 0; ; {
+		p.Lock()
+
+		ts := p.now()
+		for n := 0; n < batch && i < p.data.Len(); n++ {
+			cv := p.data.At(i).(*CacheValue)
+			if !cv.expired(ts) {
+				i++
+				continue
+			}
+
+			p.remove(cv, EventExpire)
+			removed++
+		}
+
+		// keys may be added or removed between batches, so this is
+		// approximate in the same way the expiry itself is
+		done := i >= p.data.Len()
 		p.Unlock()
+
+		if done {
+			return removed
+		}
+	}
+}
+
+// EvictExpired removes expired keys and returns how many were removed. It
+// stops early once maxEntries keys are removed or after maxDuration has
+// passed, whichever comes first; 0 means no limit. This lets callers
+// schedule sweeps themselves instead of using PeriodicEvictionInterval.
+func (p *Cache) EvictExpired(maxEntries int, maxDuration time.Duration) int {
+	start := time.Now()
+	removed := 0
+
+	p.Lock()
+	defer p.Unlock()
+
+	ts := p.now()
+	for i, n := 0, 0; i < p.data.Len(); n++ {
+		if maxEntries > 0 && removed >= maxEntries {
+			break
+		}
+
+		// checking the clock is relatively costly, do it in strides. i
+		// stays put while keys are removed so count iterations instead.
+		if maxDuration > 0 && n%64 == 0 && time.Since(start) > maxDuration {
+			break
+		}
+
+		cv := p.data.At(i).(*CacheValue)
+		if !cv.expired(ts) {
+			i++
+			continue
+		}
+
+		// removing shifts the following keys down, so don't advance i
+		p.remove(cv, EventExpire)
+		removed++
 	}
+
+	return removed
 }
 
+// Put stores key for Duration seconds, or as long as TTLFunc returns. A
+// value that can't be stored, e.g. because it is larger than
+// MaxValueBytes, is dropped; Set reports why.
 func (p *Cache) Put(key string, value interface{}) {
-	p.PutWithExpiry(key, value, p.Duration)
+	p.Set(key, value)
 }
 
-func (p *Cache) PutWithExpiry(key string, value interface{}, duration int) {
+// Set is like Put but returns the error that kept the value from being
+// stored, if any
+func (p *Cache) Set(key string, value interface{}) error {
+	if p.latency != nil {
+		defer p.observe(&p.latency.put, time.Now())
+	}
+
+	if len(p.middleware) > 0 {
+		return p.chain(Handler{Get: p.lookup, Put: p.putDefault}).Put(key,
+			value)
+	}
+	return p.putDefault(key, value)
+}
+
+// Swap is like Put but also returns the value key had before and whether
+// it was in the cache, so callers can clean up the value it replaced. The
+// replaced value is the caller's to clean up: it isn't passed to Disposer,
+// unless CloneFunc or Codec are set and the caller got a copy of it.
+func (p *Cache) Swap(key string, value interface{}) (interface{}, bool,
+	error) {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return nil, false, err
+	}
+
 	p.Lock()
+	defer p.Unlock()
+	o := p.defaultOptions(key, value)
+	o.handOver = true
+	return p.put(key, value, o)
+}
+
+// defaultOptions are the options to store key with when the caller doesn't
+// give a duration
+func (p *Cache) defaultOptions(key string, value interface{}) entryOptions {
+	return entryOptions{duration: p.defaultDuration(key, value),
+		adaptive: true}
+}
+
+// defaultDuration is the duration in seconds to store key for when the
+// caller doesn't give one
+func (p *Cache) defaultDuration(key string, value interface{}) int {
+	if p.TTLFunc != nil {
+		if d := p.TTLFunc(key, value); d > 0 {
+			return seconds(d)
+		}
+	}
 
+	return p.Duration
+}
+
+// PutWithExpiry stores key for duration seconds. A duration of 0 or less
+// stores it until it is removed or evicted.
+func (p *Cache) PutWithExpiry(key string, value interface{}, duration int) {
+	p.SetWithPriority(key, value, duration, 0)
+}
+
+// SetWithExpiry is like PutWithExpiry but returns the error that kept
+// the value from being stored, if any
+func (p *Cache) SetWithExpiry(key string, value interface{},
+	duration int) error {
+	return p.SetWithPriority(key, value, duration, 0)
+}
+
+// PutWithPriority is like PutWithExpiry but also sets the eviction priority
+// of the key. When the cache is full, keys with a lower priority are evicted
+// before those with a higher one and the expiry time breaks ties.
+func (p *Cache) PutWithPriority(key string, value interface{}, duration int,
+	priority int) {
+	p.SetWithPriority(key, value, duration, priority)
+}
+
+// SetWithPriority is like PutWithPriority but returns the error that
+// kept the value from being stored, if any
+func (p *Cache) SetWithPriority(key string, value interface{},
+	duration int, priority int) error {
+	if p.latency != nil {
+		defer p.observe(&p.latency.put, time.Now())
+	}
+
+	o := entryOptions{duration: duration, priority: priority}
+	if len(p.middleware) > 0 {
+		put := func(key string, value interface{}) error {
+			return p.putWith(key, value, o)
+		}
+		return p.chain(Handler{Get: p.lookup, Put: put}).Put(key, value)
+	}
+	return p.putWith(key, value, o)
+}
+
+// entryOptions are the settings a key is stored with
+type entryOptions struct {
+	duration int   // seconds until the key expires, <= 0 for never
+	expireAt int64 // when the key expires, overrides duration if set
+	priority int
+	soft     int // seconds until the key is stale, 0 for never
+	metadata map[string]string
+	adaptive bool     // duration may be replaced as set by AdaptiveTTL
+	deps     []string // keys whose removal removes this one
+	loaded   bool     // the value is from Loader, see putLoaded
+	moved    bool     // expireAt is kept as is, see Rename
+	handOver bool     // the replaced value goes to the caller, see Swap
+}
+
+// put must be called with the lock held. It returns the value key had
+// before, if any.
+func (p *Cache) put(key string, value interface{},
+	o entryOptions) (interface{}, bool, error) {
+	if p.CheckInvariants {
+		defer p.checkInvariants("put")
+	}
+
+	if p.closed {
+		return nil, false, ErrClosed
+	}
+
+	key = p.canonicalKey(key)
+	if err := p.validateKey(key); err != nil {
+		return nil, false, err
+	}
+
+	if o.loaded && (p.tombstoned(key) || p.find(key) != nil) {
+		return nil, false, nil
+	}
+	delete(p.tombstones, key)
+
+	value, err := p.encode(value)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ts := p.now()
+
+	_v := p.find(key)
+	if _v != nil && o.adaptive && p.AdaptiveTTL {
+		o.duration = p.adaptDuration(_v)
+	}
+
+	// an ExpireAt of 0 means the key never expires
+	expireAt := o.expireAt
+	if expireAt == 0 && o.duration > 0 {
+		expireAt = ts + int64(p.jitter(o.duration))
+	}
+
+	// a moved key already had the bounds and rounding applied
+	if !o.moved {
+		expireAt, err = p.boundExpiry(ts, expireAt)
+		if err != nil {
+			return nil, false, err
+		}
+		expireAt = p.quantize(expireAt)
+	}
+
+	var ttl int64
+	if expireAt != 0 {
+		ttl = expireAt - ts
+	}
+
+	duration := ttl
+	if o.expireAt == 0 && o.duration > 0 {
+		duration = int64(o.duration)
+	}
+
+	var staleAt int64
+	if o.soft != 0 {
+		staleAt = ts + int64(o.soft)
+	}
+
+	// If already exists, update value and expiry
+	if _v != nil {
+		old := p.clone(_v.Value)
+		if o.handOver {
+			p.handOver(_v)
+		}
+		p.setValue(_v, value)
+		_v.Priority = o.priority
+		_v.ExpireAt = expireAt
+		p.scheduleExpiry(_v)
+		p.depend(_v, o.deps)
+		_v.StaleAt = staleAt
+		_v.ttl = ttl
+		_v.duration = duration
+		_v.softTTL = int64(o.soft)
+		_v.Metadata = o.metadata
+		_v.writeHits = _v.HitCount
+		_v.Version = p.nextVersion()
+		if p.policy != nil {
+			p.policy.OnAccess(_v)
+		}
+		p.publish(p.event(EventUpdate, _v))
+		return old, true, nil
+	}
+
+	c := p.costOf(key, value)
+	t := p.tenantOf(key)
+	tenantFull := t != nil && len(t.keys) >= t.quota
+	if p.full() || p.overCost(c) || tenantFull {
+		switch {
+		case p.WritePolicy == DropOnFull:
+			return nil, false, nil
+		case p.WritePolicy == RejectOnFull:
+			return nil, false, ErrCacheFull
+		case p.Admitter != nil && !p.Admitter.Admit(key):
+			return nil, false, nil
+		}
+	}
+
+	if tenantFull {
+		p.evictTenant(t)
+	}
 	p.update()
+	for p.overCost(c) && p.evictKey() {
+	}
 
-	v := CacheValue{ExpireAt: time.Now().UTC().Unix() + int64(duration),
-		Key: key, Value: value}
+	if p.full() || p.overCost(c) {
+		if err := p.saturated(c); err != nil {
+			return nil, false, err
+		}
+	}
+
+	v := CacheValue{ExpireAt: expireAt, StaleAt: staleAt,
+		Key: key, Value: value, Priority: o.priority, CreatedAt: ts,
+		Version: p.nextVersion(), ttl: ttl, duration: duration,
+		softTTL: int64(o.soft), cost: c, Metadata: o.metadata}
 
 	// Add kv to data
-	av, is_dup := p.data.Add(&v)
-	if is_dup {
-		// If already exists, update value
-		_v := av.(*CacheValue)
-		_v.Value = value
+	p.data.Add(&v)
+	p.keys[key] = &v
+	atomic.AddInt64(&p.size, 1)
+	if p.filter != nil {
+		p.filter.add(key)
 	}
-
-	p.Unlock()
+	p.cost += c
+	if p.policy != nil {
+		p.policy.OnAdd(&v)
+	}
+	if t != nil {
+		t.keys[key] = &v
+	}
+	p.reindex(&v)
+	p.scheduleExpiry(&v)
+	p.track(&v)
+	p.depend(&v, o.deps)
+	if p.group != nil {
+		p.group.notify()
+	}
+	p.publish(p.event(EventPut, &v))
+	return nil, false, nil
 }
 
 func (p *Cache) Get(key string) interface{} {
-	var r interface{} = nil
+	if p.latency != nil {
+		defer p.observe(&p.latency.get, time.Now())
+	}
+
+	r, _ := p.Lookup(key)
+	return r
+}
+
+// Lookup is like Get but also reports whether key is in the cache, so a
+// stored nil value can be told apart from a missing key
+func (p *Cache) Lookup(key string) (interface{}, bool) {
+	if len(p.middleware) > 0 {
+		return p.chain(Handler{Get: p.lookup, Put: p.putDefault}).Get(key)
+	}
+	return p.lookup(key)
+}
+
+// get must be called with the lock held
+func (p *Cache) get(key string) (interface{}, bool) {
+	cv := p.access(key)
+	if cv == nil {
+		return nil, false
+	}
+
+	return p.clone(cv.Value), true
+}
+
+// access returns the entry for key, if any, recording the access. It must
+// be called with the lock held.
+func (p *Cache) access(key string) *CacheValue {
+	cv := p.find(p.canonicalKey(key))
+	if cv == nil {
+		return nil
+	}
+
+	cv.LastAccessedAt = p.now()
+	cv.HitCount++
+	atomic.AddUint64(&p.hits, 1)
+	if p.hot != nil {
+		p.hot.record(cv.Key)
+	}
+	if p.policy != nil {
+		p.policy.OnAccess(cv)
+	}
+	p.maybeRefresh(cv)
+	return cv
+}
+
+// GetEntry returns a copy of the entry stored for key, including its
+// access metadata. It does not count as an access itself.
+func (p *Cache) GetEntry(key string) (*CacheValue, bool) {
 	p.Lock()
+	defer p.Unlock()
 
-	v := p.data.Find(&CacheValue{Key: key})
-	if v != nil {
-		r = v.(*CacheValue).Value
+	v := p.find(p.canonicalKey(key))
+	if v == nil {
+		return nil, false
 	}
 
-	p.Unlock()
-	return r
+	cv := *v
+	cv.Value = p.clone(cv.Value)
+	cv.Metadata = copyMetadata(cv.Metadata)
+	return &cv, true
 }
 
+// Del removes key
 func (p *Cache) Del(key string) {
+	p.DelValue(key)
+}
+
+// DelValue is like Del but also returns the value key had and whether it
+// was in the cache
+func (p *Cache) DelValue(key string) (interface{}, bool) {
 	p.Lock()
-	p.data.Remove(&CacheValue{Key: key})
-	p.Unlock()
+	defer p.Unlock()
+
+	v := p.find(p.canonicalKey(key))
+	if v == nil {
+		return nil, false
+	}
+
+	p.remove(v, EventDelete)
+	p.bury(v.Key)
+	return p.clone(v.Value), true
+}
+
+// Pop removes key and returns its value in one step, so no two callers can
+// both get it. Unlike Get and DelValue it doesn't return expired values,
+// which makes it suited to one-time tokens.
+func (p *Cache) Pop(key string) (interface{}, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	cv := p.find(p.canonicalKey(key))
+	if cv == nil {
+		return nil, false
+	}
+
+	if cv.expired(p.now()) {
+		p.remove(cv, EventExpire)
+		return nil, false
+	}
+
+	p.remove(cv, EventDelete)
+	return p.clone(cv.Value), true
 }
 
 func (p *Cache) PopRandom() interface{} {
@@ -127,12 +991,12 @@ func (p *Cache) PopRandom() interface{} {
 
 	length := p.data.Len()
 	if length != 0 {
-		index := rand.Intn(p.data.Len())
+		index := p.intn(p.data.Len())
 
 		v := p.data.At(index).(*CacheValue)
-		p.data.Remove(v)
+		p.remove(v, EventDelete)
 
-		r = v.Value
+		r = p.clone(v.Value)
 	}
 
 	p.Unlock()
@@ -140,67 +1004,259 @@ func (p *Cache) PopRandom() interface{} {
 }
 
 func (p *Cache) Exists(key string) bool {
+	if p.absent(key) {
+		return false
+	}
+
 	p.Lock()
-	v := p.data.Find(&CacheValue{Key: key})
+	v := p.find(p.canonicalKey(key))
 	p.Unlock()
 	return v != nil
 }
 
+// Count returns the number of keys in the cache, including expired keys
+// not yet removed. It doesn't take the lock, so it is cheap to call often.
 func (p *Cache) Count() int {
-	p.Lock()
-	count := p.data.Len()
-	p.Unlock()
-	return count
+	return int(atomic.LoadInt64(&p.size))
 }
 
+// Iter returns a channel yielding copies of all entries as they were when
+// Iter was called, so the cache can be modified while iterating.
 func (p *Cache) Iter() <-chan *CacheValue {
+	p.Lock()
+	entries := make([]*CacheValue, 0, p.data.Len())
+	for i := 0; i < p.data.Len(); i++ {
+		cv := *p.data.At(i).(*CacheValue)
+		cv.Value = p.clone(cv.Value)
+		cv.Metadata = copyMetadata(cv.Metadata)
+		entries = append(entries, &cv)
+	}
+	p.Unlock()
+
 	wc := make(chan *CacheValue)
-	rc := p.data.Iter()
+	p.background(func() {
+		for _, v := range entries {
+			wc <- v
+		}
 
-	go func() {
-		for v := range rc {
-			wc <- v.(*CacheValue)
+		close(wc)
+	})
+
+	return wc
+}
+
+// IterSnapshot is like Iter but only copies the entries themselves while
+// holding the lock, so writers are blocked for as short a time as possible
+// even for a big cache. Values are never modified in place, only replaced,
+// so each one is copied as it is read instead.
+func (p *Cache) IterSnapshot() <-chan *CacheValue {
+	p.Lock()
+	entries := p.entries()
+	p.Unlock()
+
+	return p.yieldEntries(entries)
+}
+
+// entries returns shallow copies of all entries. It must be called with
+// the lock held.
+func (p *Cache) entries() []CacheValue {
+	entries := make([]CacheValue, p.data.Len())
+	for i := range entries {
+		entries[i] = *p.data.At(i).(*CacheValue)
+	}
+	return entries
+}
+
+// yieldEntries sends copies of entries on the returned channel, which is
+// closed after the last one
+func (p *Cache) yieldEntries(entries []CacheValue) <-chan *CacheValue {
+	wc := make(chan *CacheValue)
+	p.background(func() {
+		for i := range entries {
+			cv := &entries[i]
+			cv.Value = p.clone(cv.Value)
+			cv.Metadata = copyMetadata(cv.Metadata)
+			wc <- cv
 		}
 
 		close(wc)
-	}()
+	})
 
 	return wc
 }
 
-func (p *Cache) evictKey() {
-	n := p.NSamples
-	if n == 0 {
-		n = 1
+// evictKey evicts the key chosen by victim, reporting false if there is
+// none to evict
+func (p *Cache) evictKey() bool {
+	v := p.victim()
+	if v == nil {
+		return false
 	}
 
-	// init min ts to a big value in the future (for min ts finding
-	// logic below to work)
-	var min_ts int64 = time.Now().UTC().Unix() + (365 * 86400)
+	p.remove(v, EventEvict)
+	p.evicted++
+	return true
+}
+
+// victim returns the key to evict next, or nil if there is none that isn't
+// protected by ProtectedPriority
+func (p *Cache) victim() *CacheValue {
 	var min_v *CacheValue = nil
 
-	for i := 0; i < n; i++ {
-		v := p.data.At(rand.Intn(p.data.Len())).(*CacheValue)
-		if v.ExpireAt < min_ts {
-			min_ts = v.ExpireAt
+	if p.policy != nil {
+		min_v = p.policy.Victim()
+	} else if p.data.Len() > 0 {
+		n := p.NSamples
+		if n == 0 {
+			n = 1
+		}
+
+		// pick the lowest priority key among the samples, preferring
+		// the one expiring soonest when priorities are equal
+		if p.EvictSoonest {
+			min_v = p.lowest()
+		} else {
+			for i := 0; i < n; i++ {
+				v := p.data.At(p.intn(p.data.Len())).(*CacheValue)
+				if min_v == nil || evictsBefore(v, min_v) {
+					min_v = v
+				}
+			}
+		}
+	}
+
+	// look further only if the choice is protected, the lowest key being
+	// protected means all are
+	if min_v == nil || p.protected(min_v) {
+		if min_v = p.lowest(); min_v != nil && p.protected(min_v) {
+			return nil
+		}
+	}
+	return min_v
+}
+
+// lowest returns the key evicted first by priority and expiry, of all keys
+func (p *Cache) lowest() *CacheValue {
+	var min_v *CacheValue
+	for i := 0; i < p.data.Len(); i++ {
+		v := p.data.At(i).(*CacheValue)
+		if min_v == nil || evictsBefore(v, min_v) {
 			min_v = v
 		}
 	}
+	return min_v
+}
 
-	if min_v != nil {
-		p.data.Remove(min_v)
+// evictsBefore reports whether a should be evicted before b. Keys are
+// compared last so that the order is total.
+func evictsBefore(a, b *CacheValue) bool {
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
 	}
+
+	if a.expiry() != b.expiry() {
+		return a.expiry() < b.expiry()
+	}
+
+	return a.Key < b.Key
+}
+
+func (p *Cache) intn(n int) int {
+	if p.rnd != nil {
+		return p.rnd.Intn(n)
+	}
+	return rand.Intn(n)
+}
+
+func (p *Cache) float64() float64 {
+	if p.rnd != nil {
+		return p.rnd.Float64()
+	}
+	return rand.Float64()
+}
+
+// remove drops cv from the cache, publishing an event of type typ
+func (p *Cache) remove(cv *CacheValue, typ EventType) {
+	p.data.Remove(cv)
+	delete(p.keys, cv.Key)
+	atomic.AddInt64(&p.size, -1)
+	if p.filter != nil {
+		p.filter.remove(cv.Key)
+	}
+	if p.room != nil {
+		p.room.Broadcast()
+	}
+	p.cost -= cv.cost
+	p.unindex(cv)
+	p.stopExpiry(cv)
+	p.untrack(cv)
+	if p.policy != nil {
+		p.policy.OnRemove(cv, typ)
+	}
+	if t := p.tenantOf(cv.Key); t != nil {
+		delete(t.keys, cv.Key)
+	}
+	p.undepend(cv)
+	p.publish(p.event(typ, cv))
+	p.invalidateDependents(cv.Key)
+
+	if p.CheckInvariants {
+		p.checkInvariants("remove")
+	}
+}
+
+func now() int64 {
+	return time.Now().UTC().Unix()
+}
+
+// clock returns the current time as given by Clock
+func (p *Cache) clock() time.Time {
+	if p.Clock != nil {
+		return p.Clock()
+	}
+	return time.Now()
+}
+
+// now returns the current time as given by Clock in Unix seconds
+func (p *Cache) now() int64 {
+	return p.clock().Unix()
+}
+
+func (p *Cache) jitter(duration int) int {
+	if p.TTLJitter <= 0 {
+		return duration
+	}
+
+	f := 1 + p.TTLJitter*(2*p.float64()-1)
+	d := int(math.Round(float64(duration) * f))
+	if d < 1 {
+		// don't turn a short duration into no expiry at all
+		d = 1
+	}
+	return d
+}
+
+func (p *Cache) full() bool {
+	if p.HighWatermark > 0 {
+		return p.data.Len() >= p.HighWatermark
+	}
+	return p.Max != 0 && p.data.Len() >= p.Max
 }
 
 func (p *Cache) update() {
 
-	if p.Max == 0 || p.data.Len() < p.Max {
+	if !p.full() {
+		return
+	}
+
+	if p.HighWatermark > 0 {
+		for p.data.Len() > p.LowWatermark && p.evictKey() {
+		}
 		return
 	}
 
 	// Make space by removing keys
-	// Break when keys become empty
-	for i := 0; i < p.NEvictions && p.data.Len() > 0; i++ {
-		p.evictKey()
+	// Break when no key can be evicted
+	for i := 0; i < p.NEvictions && p.evictKey(); i++ {
 	}
 }
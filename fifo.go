@@ -0,0 +1,35 @@
+package expiringcache
+
+import (
+	"container/list"
+)
+
+// fifo evicts keys in the order they were added
+type fifo struct {
+	order *list.List
+	elems map[string]*list.Element
+}
+
+func newFIFO() *fifo {
+	return &fifo{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+func (p *fifo) OnAdd(cv *CacheValue) {
+	p.elems[cv.Key] = p.order.PushFront(cv)
+}
+
+func (p *fifo) OnAccess(cv *CacheValue) {}
+
+func (p *fifo) OnRemove(cv *CacheValue, typ EventType) {
+	if e, ok := p.elems[cv.Key]; ok {
+		p.order.Remove(e)
+		delete(p.elems, cv.Key)
+	}
+}
+
+func (p *fifo) Victim() *CacheValue {
+	if e := p.order.Back(); e != nil {
+		return e.Value.(*CacheValue)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package expiringcache
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestFIFO(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 3, NEvictions: 1, Policy: PolicyFIFO}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Put("c", 3)
+	cache.Get("a")
+	cache.Put("d", 4)
+
+	if cache.Exists("a") || !cache.Exists("b") {
+		t.Errorf("Key added first not evicted first")
+	}
+}
+
+func TestRandomPolicy(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 10, NEvictions: 1,
+		Policy: PolicyRandom, RandSource: rand.NewSource(1)}
+	cache.Init()
+
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+		if i%3 == 0 {
+			cache.Del(strconv.Itoa(i))
+		}
+	}
+
+	r := cache.policy.(*Random)
+	if len(r.entries) != cache.Count() || len(r.index) != cache.Count() {
+		t.Errorf("Tracking %d keys, cache has %d", len(r.entries),
+			cache.Count())
+	}
+
+	for k, i := range r.index {
+		if r.entries[i].Key != k || !cache.Exists(k) {
+			t.Errorf("Index of %q is wrong", k)
+		}
+	}
+}
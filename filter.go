@@ -0,0 +1,57 @@
+package expiringcache
+
+import (
+	"hash/maphash"
+	"sync/atomic"
+)
+
+// number of counters each key sets in the miss filter
+const missFilterHashes = 4
+
+// missFilter is a counting Bloom filter of the keys in the cache. It is
+// updated with the lock held but read without it, so Get and Exists can
+// turn away keys that are certainly missing without waiting for the lock.
+type missFilter struct {
+	counts []uint32
+	seed   maphash.Seed
+}
+
+func newMissFilter(size int) *missFilter {
+	return &missFilter{counts: make([]uint32, size), seed: maphash.MakeSeed()}
+}
+
+// slots calls fn with each counter of key, derived from one hash by double
+// hashing
+func (p *missFilter) slots(key string, fn func(i uint64)) {
+	h := maphash.String(p.seed, key)
+	h1, h2 := h&0xffffffff, h>>32|1
+	n := uint64(len(p.counts))
+	for i := uint64(0); i < missFilterHashes; i++ {
+		fn((h1 + i*h2) % n)
+	}
+}
+
+func (p *missFilter) add(key string) {
+	p.slots(key, func(i uint64) { atomic.AddUint32(&p.counts[i], 1) })
+}
+
+func (p *missFilter) remove(key string) {
+	p.slots(key, func(i uint64) { atomic.AddUint32(&p.counts[i], ^uint32(0)) })
+}
+
+// mayContain reports false if key is certainly not in the cache
+func (p *missFilter) mayContain(key string) bool {
+	found := true
+	p.slots(key, func(i uint64) {
+		if atomic.LoadUint32(&p.counts[i]) == 0 {
+			found = false
+		}
+	})
+	return found
+}
+
+// absent reports whether key is certainly not in the cache, without the
+// lock
+func (p *Cache) absent(key string) bool {
+	return p.filter != nil && !p.filter.mayContain(p.canonicalKey(key))
+}
@@ -0,0 +1,53 @@
+package expiringcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestMissFilter(t *testing.T) {
+	cache := Cache{Duration: 60, MissFilterSize: 1000}
+	cache.Init()
+
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	for i := 0; i < 100; i += 2 {
+		cache.Del(strconv.Itoa(i))
+	}
+
+	for i := 1; i < 100; i += 2 {
+		if v := cache.Get(strconv.Itoa(i)); v != i {
+			t.Errorf("Get(%d) returned %v", i, v)
+		}
+	}
+
+	// most missing keys are turned away without the lock
+	cache.Lock()
+	absent := 0
+	for i := 0; i < 100; i += 2 {
+		if cache.absent(strconv.Itoa(i)) {
+			absent++
+		}
+	}
+	cache.Unlock()
+	if absent < 40 {
+		t.Errorf("Only %d of 50 missing keys known absent", absent)
+	}
+
+	// pick a key the filter knows is absent, as the seed is random
+	missing := "missing"
+	for i := 0; !cache.absent(missing); i++ {
+		missing = "missing" + strconv.Itoa(i)
+	}
+
+	cache.Lock()
+	done := make(chan bool)
+	go func() {
+		done <- cache.Exists(missing)
+	}()
+	if exists := <-done; exists {
+		t.Errorf("Exists found a missing key")
+	}
+	cache.Unlock()
+}
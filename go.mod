@@ -0,0 +1,3 @@
+module github.com/deep-compute/expiringcache
+
+go 1.21
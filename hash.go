@@ -0,0 +1,110 @@
+package expiringcache
+
+// HSet sets field of the map stored at key to value, like a Redis hash,
+// storing a new map as with Put if key is missing. Existing keys keep their
+// expiry. It returns ErrWrongType if key holds something other than a map
+// of fields.
+func (p *Cache) HSet(key, field string, value interface{}) error {
+	p.Lock()
+	defer p.Unlock()
+
+	cv, fields, err := p.fields(key)
+	if err != nil {
+		return err
+	}
+
+	if fields == nil {
+		fields = make(map[string]interface{})
+	}
+	fields[field] = value
+	return p.storeFields(cv, key, fields)
+}
+
+// HGet returns field of the map stored at key and whether it is set
+func (p *Cache) HGet(key, field string) (interface{}, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	cv := p.access(key)
+	if cv == nil {
+		return nil, false
+	}
+
+	fields, _ := p.clone(cv.Value).(map[string]interface{})
+	value, ok := fields[field]
+	return value, ok
+}
+
+// HDel removes field from the map stored at key, removing the key with its
+// last field, and reports whether the field was set
+func (p *Cache) HDel(key, field string) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	cv, fields, err := p.fields(key)
+	if err != nil {
+		return false
+	}
+	if _, ok := fields[field]; !ok {
+		return false
+	}
+
+	delete(fields, field)
+	return p.storeFields(cv, key, fields) == nil
+}
+
+// fields returns the entry of key and a copy of its map, which is nil if
+// key is missing. It must be called with the lock held.
+func (p *Cache) fields(key string) (*CacheValue, map[string]interface{},
+	error) {
+	cv := p.find(p.canonicalKey(key))
+	if cv == nil {
+		return nil, nil, nil
+	}
+
+	stored, ok := p.clone(cv.Value).(map[string]interface{})
+	if !ok {
+		return nil, nil, ErrWrongType
+	}
+
+	// values are replaced rather than modified in place
+	fields := make(map[string]interface{}, len(stored)+1)
+	for k, v := range stored {
+		fields[k] = v
+	}
+	return cv, fields, nil
+}
+
+// storeFields stores fields as the value of key, whose entry is cv if it
+// exists, or removes key if there are none left. It must be called with
+// the lock held.
+func (p *Cache) storeFields(cv *CacheValue, key string,
+	fields map[string]interface{}) error {
+	if p.closed {
+		return ErrClosed
+	}
+
+	if len(fields) == 0 {
+		p.remove(cv, EventDelete)
+		return nil
+	}
+
+	value, err := limitValue(fields, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	if cv == nil {
+		_, _, err = p.put(key, value, p.defaultOptions(key, value))
+		return err
+	}
+
+	if value, err = p.encode(value); err != nil {
+		return err
+	}
+
+	p.setValue(cv, value)
+	cv.Version = p.nextVersion()
+	p.publish(p.event(EventUpdate, cv))
+	return nil
+}
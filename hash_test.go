@@ -0,0 +1,43 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.HSet("session", "user", "alice")
+	cache.HSet("session", "theme", "dark")
+	before, _ := cache.ExpiresAt("session")
+
+	if v, ok := cache.HGet("session", "user"); !ok || v != "alice" {
+		t.Errorf("HGet returned %v, %v", v, ok)
+	}
+	if _, ok := cache.HGet("session", "missing"); ok {
+		t.Errorf("HGet found a missing field")
+	}
+
+	fields := cache.Get("session").(map[string]interface{})
+	cache.HSet("session", "user", "bob")
+	if fields["user"] != "alice" {
+		t.Errorf("HSet modified a value returned earlier")
+	}
+	if after, _ := cache.ExpiresAt("session"); !after.Equal(before) {
+		t.Errorf("HSet changed the expiry")
+	}
+
+	if !cache.HDel("session", "user") || cache.HDel("session", "user") {
+		t.Errorf("HDel did not report the field")
+	}
+	cache.HDel("session", "theme")
+	if cache.Exists("session") {
+		t.Errorf("Key kept without fields")
+	}
+
+	cache.Put("plain", 1)
+	if err := cache.HSet("plain", "a", 1); err != ErrWrongType {
+		t.Errorf("HSet on a non-map returned %v", err)
+	}
+}
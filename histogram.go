@@ -0,0 +1,62 @@
+package expiringcache
+
+import (
+	"time"
+)
+
+// ExpiryBucket counts the keys expiring in [Start, End)
+type ExpiryBucket struct {
+	Start time.Time
+	End   time.Time
+	Count int
+}
+
+// ExpiryHistogram splits the time from now until the last key expires into
+// equal buckets and counts how many keys expire within each, to predict
+// upcoming eviction storms. Keys that have already expired are counted in
+// the first bucket and keys that never expire are not counted.
+func (p *Cache) ExpiryHistogram(buckets int) []ExpiryBucket {
+	if buckets <= 0 {
+		return nil
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.data.Len() == 0 {
+		return nil
+	}
+
+	ts := p.now()
+	last := ts
+	for i := 0; i < p.data.Len(); i++ {
+		if e := p.data.At(i).(*CacheValue).ExpireAt; e > last {
+			last = e
+		}
+	}
+
+	// round up so the last key falls within the last bucket
+	width := (last-ts)/int64(buckets) + 1
+
+	hist := make([]ExpiryBucket, buckets)
+	for i := range hist {
+		start := ts + int64(i)*width
+		hist[i].Start = time.Unix(start, 0).UTC()
+		hist[i].End = time.Unix(start+width, 0).UTC()
+	}
+
+	for i := 0; i < p.data.Len(); i++ {
+		cv := p.data.At(i).(*CacheValue)
+		if cv.ExpireAt == 0 {
+			continue
+		}
+
+		b := (cv.ExpireAt - ts) / width
+		if b < 0 {
+			b = 0
+		}
+		hist[b].Count++
+	}
+
+	return hist
+}
@@ -0,0 +1,33 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryHistogram(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	if cache.ExpiryHistogram(4) != nil {
+		t.Errorf("Histogram of empty cache not nil")
+	}
+
+	cache.PutWithDeadline("expired", 1, time.Now().Add(-5*time.Second))
+	cache.PutWithExpiry("a", 1, 10)
+	cache.PutWithExpiry("b", 1, 11)
+	cache.PutWithExpiry("c", 1, 99)
+
+	hist := cache.ExpiryHistogram(4)
+	counts := []int{3, 0, 0, 1}
+	for i, b := range hist {
+		if b.Count != counts[i] {
+			t.Errorf("Bucket %d has %d keys, expected %d", i, b.Count,
+				counts[i])
+		}
+	}
+
+	if hist[0].End != hist[1].Start {
+		t.Errorf("Buckets are not contiguous")
+	}
+}
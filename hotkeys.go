@@ -0,0 +1,128 @@
+package expiringcache
+
+import (
+	"container/heap"
+	"sort"
+)
+
+const (
+	sketchDepth = 4
+	sketchWidth = 2048
+)
+
+// KeyStat is the approximate number of times a key was fetched
+type KeyStat struct {
+	Key   string
+	Count uint64
+}
+
+// hotKeys tracks the most frequently fetched keys using a count-min
+// sketch, so memory use doesn't grow with the number of distinct keys.
+type hotKeys struct {
+	sketch [sketchDepth][sketchWidth]uint64
+	heap   hotHeap // the tracked keys, coldest on top
+	top    map[string]*hotEntry
+	size   int
+}
+
+type hotEntry struct {
+	key   string
+	count uint64
+	index int // in the heap
+}
+
+func newHotKeys(size int) *hotKeys {
+	return &hotKeys{top: make(map[string]*hotEntry, size), size: size}
+}
+
+// record counts an access to key
+func (p *hotKeys) record(key string) {
+	h := hashKey(key)
+	h1, h2 := h&0xffffffff, h>>32
+
+	var est uint64
+	for i := 0; i < sketchDepth; i++ {
+		j := (h1 + uint64(i)*h2) % sketchWidth
+		p.sketch[i][j]++
+		if i == 0 || p.sketch[i][j] < est {
+			est = p.sketch[i][j]
+		}
+	}
+
+	if e, ok := p.top[key]; ok {
+		e.count = est
+		heap.Fix(&p.heap, e.index)
+		return
+	}
+
+	if len(p.heap) < p.size {
+		e := &hotEntry{key: key, count: est}
+		p.top[key] = e
+		heap.Push(&p.heap, e)
+		return
+	}
+
+	// replace the coldest of the tracked keys if this one is hotter
+	if e := p.heap[0]; est > e.count {
+		delete(p.top, e.key)
+		e.key, e.count = key, est
+		p.top[key] = e
+		heap.Fix(&p.heap, 0)
+	}
+}
+
+// hotHeap keeps the coldest tracked key on top
+type hotHeap []*hotEntry
+
+func (h hotHeap) Len() int { return len(h) }
+
+func (h hotHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+
+func (h hotHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *hotHeap) Push(x interface{}) {
+	e := x.(*hotEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *hotHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
+
+// TopKeys returns up to n of the most frequently fetched keys, hottest
+// first. HotKeys must be set for keys to be tracked.
+func (p *Cache) TopKeys(n int) []KeyStat {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.hot == nil {
+		return nil
+	}
+
+	stats := make([]KeyStat, 0, len(p.hot.heap))
+	for _, e := range p.hot.heap {
+		stats = append(stats, KeyStat{Key: e.key, Count: e.count})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Key < stats[j].Key
+	})
+
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+
+	return stats
+}
@@ -0,0 +1,55 @@
+package expiringcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestTopKeys(t *testing.T) {
+	cache := Cache{Duration: 60, HotKeys: 3}
+	cache.Init()
+
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+
+	for i := 0; i < 100; i++ {
+		cache.Get(strconv.Itoa(i))
+		cache.Get("7")
+		if i%2 == 0 {
+			cache.Get("42")
+		}
+	}
+
+	top := cache.TopKeys(2)
+	if len(top) != 2 || top[0].Key != "7" || top[1].Key != "42" {
+		t.Fatalf("Unexpected top keys %v", top)
+	}
+
+	if top[0].Count < 101 {
+		t.Errorf("Count of hottest key %d underestimated", top[0].Count)
+	}
+}
+
+func TestTopKeysEmptyKey(t *testing.T) {
+	// repeated as the tracked keys used to be scanned in map order
+	for i := 0; i < 20; i++ {
+		cache := Cache{Duration: 60, HotKeys: 2}
+		cache.Init()
+		for _, key := range []string{"", "hot", "warm"} {
+			cache.Put(key, key)
+		}
+
+		for j := 0; j < 100; j++ {
+			cache.Get("hot")
+		}
+		cache.Get("")
+		cache.Get("warm")
+		cache.Get("warm")
+
+		top := cache.TopKeys(2)
+		if len(top) != 2 || top[0].Key != "hot" || top[1].Key != "warm" {
+			t.Fatalf("Unexpected top keys %v", top)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package httpcache provides an http.RoundTripper that caches responses
+// to GET requests in an expiringcache.Cache for as long as their
+// Cache-Control max-age allows.
+package httpcache
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/deep-compute/expiringcache"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+)
+
+// XFromCache is set on responses served from the cache
+const XFromCache = "X-From-Cache"
+
+// Transport is an http.RoundTripper that serves GET requests from Cache
+// when possible. Responses are cached only if they have status 200 and a
+// Cache-Control max-age, which is used as their duration.
+type Transport struct {
+	Cache *expiringcache.Cache
+
+	// Transport makes the actual requests. Defaults to
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// KeyFunc returns the cache key for a request. Defaults to the request
+	// URL.
+	KeyFunc func(req *http.Request) string
+}
+
+func (t *Transport) transport() http.RoundTripper {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (t *Transport) key(req *http.Request) string {
+	if t.KeyFunc != nil {
+		return t.KeyFunc(req)
+	}
+	return req.URL.String()
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.transport().RoundTrip(req)
+	}
+
+	key := t.key(req)
+	_, noCache := cacheControl(req.Header)["no-cache"]
+
+	if !noCache {
+		if b, ok := t.Cache.Get(key).([]byte); ok {
+			resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+			if err == nil {
+				resp.Header.Set(XFromCache, "1")
+				return resp, nil
+			}
+
+			t.Cache.Del(key)
+		}
+	}
+
+	resp, err := t.transport().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAge, ok := cacheable(resp)
+	if !ok {
+		return resp, nil
+	}
+
+	b, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return nil, err
+	}
+
+	t.Cache.PutWithExpiry(key, b, maxAge)
+	return resp, nil
+}
+
+// cacheable returns the max-age of resp if it may be cached
+func cacheable(resp *http.Response) (int, bool) {
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+
+	cc := cacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return 0, false
+	}
+
+	if _, ok := cc["no-cache"]; ok {
+		return 0, false
+	}
+
+	maxAge, err := strconv.Atoi(cc["max-age"])
+	if err != nil || maxAge <= 0 {
+		return 0, false
+	}
+
+	return maxAge, true
+}
+
+// cacheControl parses the Cache-Control directives in h
+func cacheControl(h http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if d == "" {
+				continue
+			}
+
+			name, value, _ := strings.Cut(d, "=")
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return cc
+}
@@ -0,0 +1,43 @@
+package httpcache
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			if r.URL.Path == "/cached" {
+				w.Header().Set("Cache-Control", "public, max-age=60")
+			}
+			io.WriteString(w, "hello")
+		}))
+	defer server.Close()
+
+	cache := expiringcache.Cache{Duration: 60}
+	cache.Init()
+	client := &http.Client{Transport: &Transport{Cache: &cache}}
+
+	for _, path := range []string{"/cached", "/cached", "/uncached", "/uncached"} {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != "hello" {
+			t.Errorf("Unexpected body %q", body)
+		}
+	}
+
+	if hits != 3 {
+		t.Errorf("Server hit %d times, expected 3", hits)
+	}
+}
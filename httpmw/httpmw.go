@@ -0,0 +1,217 @@
+// Package httpmw provides net/http middleware that caches rendered
+// responses in an expiringcache.Cache.
+package httpmw
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// XCache is set to HIT or MISS on responses passing through the middleware
+const XCache = "X-Cache"
+
+// DefaultMaxEntries is the number of responses held by the cache created
+// when WithCache isn't given
+const DefaultMaxEntries = 10000
+
+type route struct {
+	prefix string
+	ttl    int
+}
+
+type config struct {
+	cache        *expiringcache.Cache
+	ttl          int
+	routes       []route
+	vary         []string
+	maxBodyBytes int
+}
+
+// Option configures the middleware returned by Cache
+type Option func(*config)
+
+// WithCache stores responses in c. By default a cache holding up to
+// DefaultMaxEntries responses, swept of expired ones every minute, is
+// created for the middleware.
+func WithCache(c *expiringcache.Cache) Option {
+	return func(cfg *config) { cfg.cache = c }
+}
+
+// WithTTL sets the number of seconds responses are cached for, unless a
+// route says otherwise. Defaults to 60.
+func WithTTL(seconds int) Option {
+	return func(cfg *config) { cfg.ttl = seconds }
+}
+
+// WithRouteTTL caches responses for paths starting with prefix for the
+// given number of seconds. The longest matching prefix wins, and a TTL of
+// 0 disables caching for the route.
+func WithRouteTTL(prefix string, seconds int) Option {
+	return func(cfg *config) {
+		cfg.routes = append(cfg.routes, route{prefix: prefix, ttl: seconds})
+	}
+}
+
+// WithVary caches responses separately for each value of the given
+// request headers, e.g. Accept-Encoding.
+func WithVary(headers ...string) Option {
+	return func(cfg *config) { cfg.vary = append(cfg.vary, headers...) }
+}
+
+// WithMaxBodyBytes doesn't cache responses with bodies larger than n bytes.
+// Defaults to 1MB.
+func WithMaxBodyBytes(n int) Option {
+	return func(cfg *config) { cfg.maxBodyBytes = n }
+}
+
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// Cache returns a handler serving successful GET and HEAD responses of next
+// from the cache. Responses are streamed to the client as next writes them
+// while being captured for the cache. Responses setting cookies or marked
+// Cache-Control private or no-store are specific to the client and never
+// cached.
+func Cache(next http.Handler, opts ...Option) http.Handler {
+	cfg := &config{ttl: 60, maxBodyBytes: 1 << 20}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.cache == nil {
+		cfg.cache = &expiringcache.Cache{Duration: cfg.ttl,
+			Max: DefaultMaxEntries, NEvictions: 1, NSamples: 5,
+			PeriodicEvictionInterval: 60}
+		cfg.cache.Init()
+	}
+
+	// longest prefixes first
+	sort.Slice(cfg.routes, func(i, j int) bool {
+		return len(cfg.routes[i].prefix) > len(cfg.routes[j].prefix)
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ttl := cfg.routeTTL(r.URL.Path)
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) ||
+			ttl <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := cfg.key(r)
+		// Fetch rather than Get so responses past their TTL are misses
+		// even if they haven't been swept yet
+		v, err := cfg.cache.Fetch(key)
+		if resp, ok := v.(*response); err == nil && ok {
+			h := w.Header()
+			for k, v := range resp.header {
+				h[k] = v
+			}
+			h.Set(XCache, "HIT")
+			w.WriteHeader(resp.status)
+			if r.Method != http.MethodHead {
+				w.Write(resp.body)
+			}
+			return
+		}
+
+		w.Header().Set(XCache, "MISS")
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK,
+			max: cfg.maxBodyBytes}
+		next.ServeHTTP(rec, r)
+
+		if rec.status != http.StatusOK || rec.overflow ||
+			!shared(w.Header()) {
+			return
+		}
+
+		header := w.Header().Clone()
+		header.Del(XCache)
+		cfg.cache.PutWithExpiry(key, &response{status: rec.status,
+			header: header, body: rec.body}, ttl)
+	})
+}
+
+func (cfg *config) routeTTL(path string) int {
+	for _, rt := range cfg.routes {
+		if strings.HasPrefix(path, rt.prefix) {
+			return rt.ttl
+		}
+	}
+	return cfg.ttl
+}
+
+// shared reports whether a response with header h may be replayed to other
+// clients
+func shared(h http.Header) bool {
+	if len(h.Values("Set-Cookie")) > 0 {
+		return false
+	}
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if strings.EqualFold(name, "private") ||
+				strings.EqualFold(name, "no-store") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// key identifies the response to r. HEAD responses have no body to replay
+// for GETs, so the method is part of the key.
+func (cfg *config) key(r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.Method)
+	b.WriteByte(' ')
+	b.WriteString(r.Host)
+	b.WriteString(r.URL.RequestURI())
+	for _, h := range cfg.vary {
+		b.WriteByte('\n')
+		b.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+	return b.String()
+}
+
+// recorder passes a response through to the client while keeping a copy
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        []byte
+	max         int
+	overflow    bool
+	wroteHeader bool
+}
+
+func (p *recorder) WriteHeader(status int) {
+	if !p.wroteHeader {
+		p.status = status
+		p.wroteHeader = true
+	}
+	p.ResponseWriter.WriteHeader(status)
+}
+
+func (p *recorder) Write(b []byte) (int, error) {
+	p.wroteHeader = true
+	if !p.overflow {
+		if len(p.body)+len(b) > p.max {
+			p.overflow = true
+			p.body = nil
+		} else {
+			p.body = append(p.body, b...)
+		}
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+func (p *recorder) Flush() {
+	if f, ok := p.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,126 @@
+package httpmw
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	renders := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renders++
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, r.URL.Path+" "+r.Header.Get("Accept-Language"))
+	})
+
+	handler := Cache(next, WithRouteTTL("/live", 0),
+		WithVary("Accept-Language"))
+
+	get := func(path, lang string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept-Language", lang)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := get("/a", "en"); w.Header().Get(XCache) != "MISS" {
+		t.Errorf("First request not a miss")
+	}
+
+	w := get("/a", "en")
+	if w.Header().Get(XCache) != "HIT" || w.Body.String() != "/a en" ||
+		w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("Cached response not replayed correctly")
+	}
+
+	if w := get("/a", "fr"); !strings.HasSuffix(w.Body.String(), "fr") {
+		t.Errorf("Vary header ignored")
+	}
+
+	get("/live", "en")
+	get("/live", "en")
+
+	if renders != 4 {
+		t.Errorf("Rendered %d times, expected 4", renders)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := &expiringcache.Cache{Clock: func() time.Time { return now }}
+	cache.Init()
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	})
+	handler := Cache(next, WithCache(cache), WithRouteTTL("/short", 1))
+
+	get := func() string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w,
+			httptest.NewRequest(http.MethodGet, "/short", nil))
+		return w.Header().Get(XCache)
+	}
+
+	get()
+	if got := get(); got != "HIT" {
+		t.Errorf("Got %s before the TTL passed, expected HIT", got)
+	}
+
+	// the cache is never swept, the expired response must still be a miss
+	now = now.Add(2 * time.Second)
+	if got := get(); got != "MISS" {
+		t.Errorf("Got %s after the TTL passed, expected MISS", got)
+	}
+}
+
+func TestCachePrivate(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+		case "/account":
+			w.Header().Set("Cache-Control", "max-age=60, Private")
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		io.WriteString(w, r.Host+r.URL.Path)
+	})
+	handler := Cache(next)
+
+	get := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	for _, path := range []string{"/login", "/account", "/nostore"} {
+		get(http.MethodGet, path)
+		w := get(http.MethodGet, path)
+		if w.Header().Get(XCache) != "MISS" {
+			t.Errorf("Private response for %s cached", path)
+		}
+	}
+
+	get(http.MethodGet, "http://a.example/page")
+	w := get(http.MethodGet, "http://b.example/page")
+	if w.Header().Get(XCache) != "MISS" || w.Body.String() != "b.example/page" {
+		t.Errorf("Response for one host replayed for another")
+	}
+
+	get(http.MethodHead, "http://a.example/head")
+	w = get(http.MethodHead, "http://a.example/head")
+	if w.Header().Get(XCache) != "HIT" {
+		t.Errorf("HEAD response not cached")
+	}
+	w = get(http.MethodGet, "http://a.example/head")
+	if w.Header().Get(XCache) != "MISS" {
+		t.Errorf("HEAD response replayed for GET")
+	}
+}
@@ -0,0 +1,91 @@
+package expiringcache
+
+import "sort"
+
+// index maps each indexed value to the entries it was extracted from
+type index struct {
+	extract func(value interface{}) string
+	entries map[string]map[string]*CacheValue
+}
+
+func (p *Cache) initIndexes() {
+	if len(p.Indexes) == 0 {
+		return
+	}
+
+	p.indexes = make(map[string]*index, len(p.Indexes))
+	for name, extract := range p.Indexes {
+		p.indexes[name] = &index{extract: extract,
+			entries: make(map[string]map[string]*CacheValue)}
+	}
+}
+
+// reindex indexes the current value of cv. It must be called with the lock
+// held whenever the value changes.
+func (p *Cache) reindex(cv *CacheValue) {
+	if p.indexes == nil {
+		return
+	}
+
+	p.unindex(cv)
+
+	value := p.clone(cv.Value)
+	cv.indexed = make(map[string]string, len(p.indexes))
+	for name, idx := range p.indexes {
+		v := idx.extract(value)
+		if v == "" {
+			continue
+		}
+
+		entries := idx.entries[v]
+		if entries == nil {
+			entries = make(map[string]*CacheValue)
+			idx.entries[v] = entries
+		}
+		entries[cv.Key] = cv
+		cv.indexed[name] = v
+	}
+}
+
+// unindex removes cv from the indexes
+func (p *Cache) unindex(cv *CacheValue) {
+	for name, v := range cv.indexed {
+		idx := p.indexes[name]
+		delete(idx.entries[v], cv.Key)
+		if len(idx.entries[v]) == 0 {
+			delete(idx.entries, v)
+		}
+	}
+	cv.indexed = nil
+}
+
+// GetByIndex returns copies of the entries whose value gave indexed when
+// passed to the extractor of the index name (see Indexes), ordered by key,
+// e.g. all sessions of a user. Expired entries are left out.
+func (p *Cache) GetByIndex(name string, indexed string) []CacheValue {
+	p.Lock()
+	defer p.Unlock()
+
+	idx := p.indexes[name]
+	if idx == nil {
+		return nil
+	}
+
+	ts := p.now()
+	var entries []CacheValue
+	for _, cv := range idx.entries[indexed] {
+		if cv.expired(ts) {
+			continue
+		}
+
+		e := *cv
+		e.Value = p.clone(e.Value)
+		e.Metadata = copyMetadata(e.Metadata)
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
@@ -0,0 +1,49 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+type session struct {
+	User string
+}
+
+func TestGetByIndex(t *testing.T) {
+	cache := Cache{Duration: 60, Indexes: map[string]func(interface{}) string{
+		"user": func(value interface{}) string {
+			if s, ok := value.(session); ok {
+				return s.User
+			}
+			return ""
+		},
+	}}
+	cache.Init()
+
+	cache.Put("s1", session{User: "alice"})
+	cache.Put("s2", session{User: "bob"})
+	cache.Put("s3", session{User: "alice"})
+	cache.Put("other", 1)
+	cache.PutWithDeadline("s4", session{User: "alice"},
+		time.Now().Add(-time.Second))
+
+	entries := cache.GetByIndex("user", "alice")
+	if len(entries) != 2 || entries[0].Key != "s1" || entries[1].Key != "s3" {
+		t.Errorf("GetByIndex returned %v", entries)
+	}
+
+	cache.Put("s1", session{User: "bob"})
+	cache.Del("s3")
+	if entries := cache.GetByIndex("user", "alice"); len(entries) != 0 {
+		t.Errorf("Index not updated, GetByIndex returned %v", entries)
+	}
+
+	entries = cache.GetByIndex("user", "bob")
+	if len(entries) != 2 || entries[0].Value != (session{User: "bob"}) {
+		t.Errorf("GetByIndex returned %v", entries)
+	}
+
+	if entries := cache.GetByIndex("missing", "bob"); entries != nil {
+		t.Errorf("Unknown index returned %v", entries)
+	}
+}
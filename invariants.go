@@ -0,0 +1,64 @@
+package expiringcache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkInvariants panics, describing every problem found, if the
+// bookkeeping of the cache doesn't match its entries after op. It is only
+// called with CheckInvariants set, as it walks all entries. It must be
+// called with the lock held.
+func (p *Cache) checkInvariants(op string) {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if n := p.data.Len(); int64(n) != p.size {
+		fail("size is %d, %d entries", p.size, n)
+	}
+
+	var cost int64
+	for i := 0; i < p.data.Len(); i++ {
+		cv := p.data.At(i).(*CacheValue)
+		cost += cv.cost
+
+		if cv.ttl < 0 || cv.softTTL < 0 {
+			fail("%q has a negative duration", cv.Key)
+		}
+		if p.ExactExpiry && cv.ExpireAt != 0 && cv.timer == nil &&
+			cv.bucket == 0 {
+			fail("%q expires without a timer", cv.Key)
+		}
+		for name, v := range cv.indexed {
+			if p.indexes[name].entries[v][cv.Key] != cv {
+				fail("%q missing from index %s", cv.Key, name)
+			}
+		}
+		for _, dep := range cv.deps {
+			if _, ok := p.dependents[dep][cv.Key]; !ok {
+				fail("%q missing from the dependents of %q", cv.Key, dep)
+			}
+		}
+	}
+	if cost != p.cost {
+		fail("cost is %d, entries cost %d", p.cost, cost)
+	}
+
+	for name, idx := range p.indexes {
+		for v, entries := range idx.entries {
+			for key, cv := range entries {
+				if p.find(key) != cv {
+					fail("index %s holds removed key %q for %q", name, key,
+						v)
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		panic(fmt.Sprintf("expiringcache: invariants violated after %s:\n%s",
+			op, strings.Join(problems, "\n")))
+	}
+}
@@ -0,0 +1,38 @@
+package expiringcache
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 10, NEvictions: 2, CheckInvariants: true,
+		ExactExpiry: true, Indexes: map[string]func(interface{}) string{
+			"parity": func(v interface{}) string {
+				return strconv.Itoa(v.(int) % 2)
+			}}}
+	cache.Init()
+
+	for i := 0; i < 50; i++ {
+		key := strconv.Itoa(i % 15)
+		cache.PutWithDeps(key, i, 60, strconv.Itoa(i%7))
+		if i%3 == 0 {
+			cache.Del(strconv.Itoa(i % 5))
+		}
+	}
+	cache.Close()
+
+	defer func() {
+		r := recover()
+		if s, _ := r.(string); !strings.Contains(s, "size is") {
+			t.Errorf("Corruption not detected: %v", r)
+		}
+	}()
+
+	cache = Cache{Duration: 60, CheckInvariants: true}
+	cache.Init()
+	cache.Put("a", 1)
+	cache.size++
+	cache.Put("b", 2)
+}
@@ -0,0 +1,151 @@
+package expiringcache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+var keyEscaper = strings.NewReplacer(`\`, `\\`, `:`, `\:`)
+
+// Key builds a cache key from parts, formatted as with fmt's %v and joined
+// with ':'. Separators within parts are escaped so different parts never
+// give the same key, e.g. Key("a:b", "c") != Key("a", "b:c").
+func Key(parts ...interface{}) string {
+	return KeyBuilder{}.Key(parts...)
+}
+
+// KeyBuilder builds keys like Key with optional hashing
+type KeyBuilder struct {
+	Prefix string // added to every key, unescaped and never hashed
+	Hash   bool   // replace keys with their hex encoded SHA-256
+	MaxLen int    // hash keys longer than this many bytes, 0 for no limit
+}
+
+func (p KeyBuilder) Key(parts ...interface{}) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		keyEscaper.WriteString(&b, fmt.Sprint(part))
+	}
+
+	key := b.String()
+	if p.Hash || (p.MaxLen > 0 && len(p.Prefix)+len(key) > p.MaxLen) {
+		sum := sha256.Sum256([]byte(key))
+		key = hex.EncodeToString(sum[:])
+
+		if p.MaxLen > 0 && len(p.Prefix)+len(key) > p.MaxLen {
+			n := p.MaxLen - len(p.Prefix)
+			if n < 0 {
+				n = 0
+			}
+			key = key[:n]
+		}
+	}
+
+	return p.Prefix + key
+}
+
+// Uint64Key returns the key for n, its 8 big-endian bytes, so that integer
+// keys sort numerically.
+func Uint64Key(n uint64) string {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], n)
+	return string(b[:])
+}
+
+// canonicalKey returns key as stored, after KeyTransform
+func (p *Cache) canonicalKey(key string) string {
+	if p.KeyTransform == nil {
+		return key
+	}
+	return p.KeyTransform(key)
+}
+
+// validateKey checks key against MaxKeyLength, RejectEmptyKeys and
+// KeyRunes
+func (p *Cache) validateKey(key string) error {
+	switch {
+	case p.MaxKeyLength > 0 && len(key) > p.MaxKeyLength:
+		return fmt.Errorf("%w: longer than %d bytes", ErrInvalidKey,
+			p.MaxKeyLength)
+	case p.RejectEmptyKeys && key == "":
+		return fmt.Errorf("%w: empty", ErrInvalidKey)
+	}
+
+	if p.KeyRunes != nil {
+		for _, r := range key {
+			if !p.KeyRunes(r) {
+				return fmt.Errorf("%w: %q not allowed", ErrInvalidKey, r)
+			}
+		}
+	}
+
+	return nil
+}
+
+// bytesKey returns key as a string without copying it. The result must not
+// outlive the call it is used in, as the caller may modify key afterwards.
+func bytesKey(key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	return unsafe.String(&key[0], len(key))
+}
+
+// GetBytes is like Get for a []byte key, without copying the key
+func (p *Cache) GetBytes(key []byte) interface{} {
+	return p.Get(bytesKey(key))
+}
+
+// PutBytes is like Set for a []byte key
+func (p *Cache) PutBytes(key []byte, value interface{}) error {
+	return p.Set(string(key), value)
+}
+
+// DelBytes is like Del for a []byte key, without copying the key
+func (p *Cache) DelBytes(key []byte) {
+	p.Del(bytesKey(key))
+}
+
+// ExistsBytes is like Exists for a []byte key, without copying the key
+func (p *Cache) ExistsBytes(key []byte) bool {
+	return p.Exists(bytesKey(key))
+}
+
+// GetUint64 is like Get for the key Uint64Key(key)
+func (p *Cache) GetUint64(key uint64) interface{} {
+	return p.Get(Uint64Key(key))
+}
+
+// PutUint64 is like Set for the key Uint64Key(key)
+func (p *Cache) PutUint64(key uint64, value interface{}) error {
+	return p.Set(Uint64Key(key), value)
+}
+
+// DelUint64 is like Del for the key Uint64Key(key)
+func (p *Cache) DelUint64(key uint64) {
+	p.Del(Uint64Key(key))
+}
+
+// ExistsUint64 is like Exists for the key Uint64Key(key)
+func (p *Cache) ExistsUint64(key uint64) bool {
+	return p.Exists(Uint64Key(key))
+}
+
+// find returns the entry of key, which must be canonical, or nil. It must
+// be called with the lock held.
+//
+// Searching data needs a probe entry, which allocates, so entries are
+// found in keys instead (see BenchmarkGet). keys points to the same
+// entries rather than copies. data is still needed for what a map can't
+// do: iterating in key order and picking entries by position when
+// sampling for eviction or sweeping in batches.
+func (p *Cache) find(key string) *CacheValue {
+	return p.keys[key]
+}
@@ -0,0 +1,159 @@
+package expiringcache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	if k := Key("user", 42, "profile"); k != "user:42:profile" {
+		t.Errorf("Unexpected key %q", k)
+	}
+
+	if Key("a:b", "c") == Key("a", "b:c") || Key(`a\`, "b") == Key(`a\:b`) {
+		t.Errorf("Different parts produced the same key")
+	}
+
+	b := KeyBuilder{Prefix: "v1/", MaxLen: 20}
+	if k := b.Key("short"); k != "v1/short" {
+		t.Errorf("Short key %q changed", k)
+	}
+
+	long := b.Key(strings.Repeat("x", 100))
+	if len(long) != 20 || !strings.HasPrefix(long, "v1/") {
+		t.Errorf("Long key %q not hashed to the limit", long)
+	}
+
+	if h := (KeyBuilder{Hash: true}).Key("a"); len(h) != 64 {
+		t.Errorf("Hashed key %q is not a SHA-256", h)
+	}
+}
+
+func TestBinaryKeys(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	key := []byte("abc")
+	cache.PutBytes(key, 1)
+	key[0] = 'x' // the cache must not alias the caller's key
+
+	if cache.GetBytes([]byte("abc")).(int) != 1 || cache.ExistsBytes(key) {
+		t.Errorf("[]byte key not stored by value")
+	}
+
+	cache.PutUint64(256, 2)
+	cache.PutUint64(1, 3)
+	if cache.GetUint64(256).(int) != 2 || !cache.ExistsUint64(1) {
+		t.Errorf("Get of uint64 key failed")
+	}
+
+	if Uint64Key(1) >= Uint64Key(256) {
+		t.Errorf("uint64 keys do not sort numerically")
+	}
+
+	cache.DelUint64(1)
+	cache.DelBytes([]byte("abc"))
+	if cache.Count() != 1 {
+		t.Errorf("Del of binary keys failed")
+	}
+}
+
+func FuzzKey(f *testing.F) {
+	f.Add("a:b", "c", "a", "b:c", 0)
+	f.Add(`a\`, "b", `a\:b`, "", 20)
+
+	f.Fuzz(func(t *testing.T, a, b, c, d string, maxLen int) {
+		if (a != c || b != d) && Key(a, b) == Key(c, d) {
+			t.Errorf("Key(%q, %q) == Key(%q, %q)", a, b, c, d)
+		}
+
+		kb := KeyBuilder{Prefix: "p/", MaxLen: maxLen % 128}
+		if k := kb.Key(a, b); kb.MaxLen > len(kb.Prefix) &&
+			len(k) > kb.MaxLen {
+			t.Errorf("Key %q longer than MaxLen %d", k, kb.MaxLen)
+		}
+	})
+}
+
+func FuzzBytesKey(f *testing.F) {
+	f.Add([]byte("key"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, key []byte) {
+		cache := Cache{Duration: 60}
+		cache.Init()
+
+		stored := string(key)
+		cache.PutBytes(key, 1)
+		for i := range key {
+			key[i]++
+		}
+
+		if cache.Get(stored) != 1 {
+			t.Errorf("Key %q changed by modifying the slice", stored)
+		}
+
+		cache.DelBytes([]byte(stored))
+		if cache.Exists(stored) {
+			t.Errorf("DelBytes(%q) didn't remove the key", stored)
+		}
+	})
+}
+
+func TestKeyTransform(t *testing.T) {
+	cache := Cache{Duration: 60, KeyTransform: func(key string) string {
+		return strings.ToLower(strings.TrimSpace(key))
+	}}
+	cache.Init()
+
+	cache.Put(" User:1", 1)
+	if cache.Get("user:1") != 1 || !cache.Exists("USER:1 ") {
+		t.Errorf("Differently formatted key missed")
+	}
+	if d, ok := cache.TTL("User:1"); !ok || d <= 0 {
+		t.Errorf("TTL missed the key")
+	}
+
+	cache.Put("USER:1", 2)
+	if cache.Count() != 1 || cache.Get("user:1") != 2 {
+		t.Errorf("Put added a second key instead of replacing")
+	}
+
+	if _, ok := cache.DelValue(" user:1 "); !ok || cache.Count() != 0 {
+		t.Errorf("Del missed the key")
+	}
+}
+
+func TestKeyValidation(t *testing.T) {
+	cache := Cache{Duration: 60, MaxKeyLength: 8, RejectEmptyKeys: true,
+		KeyRunes: func(r rune) bool { return r > ' ' && r < 0x7f }}
+	cache.Init()
+
+	if err := cache.Set("user:1", 1); err != nil {
+		t.Errorf("Valid key rejected: %v", err)
+	}
+
+	for _, key := range []string{"", "user:123456", "a b", "naïve"} {
+		if err := cache.Set(key, 1); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put of %q returned %v", key, err)
+		}
+	}
+	if cache.Count() != 1 {
+		t.Errorf("Invalid keys were stored")
+	}
+}
+
+func TestFindDoesNotAllocate(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+	cache.Put("a", 1)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		cache.Exists("a")
+		cache.Exists("b")
+	})
+	if allocs != 0 {
+		t.Errorf("Exists allocated %v times", allocs)
+	}
+}
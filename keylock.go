@@ -0,0 +1,27 @@
+package expiringcache
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+const defaultKeyLockStripes = 256
+
+func (p *Cache) initKeyLocks() {
+	n := p.KeyLockStripes
+	if n <= 0 {
+		n = defaultKeyLockStripes
+	}
+
+	p.keyLocks = make([]sync.Mutex, n)
+}
+
+// KeyLock returns a lock for key, which callers doing cache-aside can hold
+// while recomputing a missing value so that only one goroutine computes it.
+// Locks are striped by a hash of the key so unrelated keys may share one;
+// don't hold one lock while acquiring another.
+func (p *Cache) KeyLock(key string) sync.Locker {
+	h := fnv.New32a()
+	h.Write([]byte(p.canonicalKey(key)))
+	return &p.keyLocks[h.Sum32()%uint32(len(p.keyLocks))]
+}
@@ -0,0 +1,38 @@
+package expiringcache
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestKeyLock(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	if cache.KeyLock("a") != cache.KeyLock("a") {
+		t.Errorf("Different locks returned for the same key")
+	}
+
+	computed := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			l := cache.KeyLock("a")
+			l.Lock()
+			defer l.Unlock()
+
+			if !cache.Exists("a") {
+				computed++
+				cache.Put("a", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if computed != 1 {
+		t.Errorf("Value computed %d times, expected once", computed)
+	}
+}
@@ -0,0 +1,111 @@
+package expiringcache
+
+import (
+	"context"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Latencies are the latencies of the operations of a cache since Init,
+// tracked if TrackLatency is set
+type Latencies struct {
+	Get  LatencySummary
+	Put  LatencySummary // Put, PutWithExpiry and PutWithPriority
+	Load LatencySummary // calls of Loader
+}
+
+// LatencySummary describes the latencies of one operation. Percentiles are
+// accurate to within 25%, rounded up.
+type LatencySummary struct {
+	Count         uint64
+	P50, P95, P99 time.Duration
+}
+
+// Latencies are tracked in buckets of 4 per power of two nanoseconds, as
+// in HDR histograms, so recording is a single atomic add
+const latencySubBuckets = 4
+
+type latencyHistogram struct {
+	counts [64 * latencySubBuckets]uint64
+}
+
+type latencies struct {
+	get, put, load latencyHistogram
+}
+
+// latencyBucket returns the bucket of d
+func latencyBucket(d time.Duration) int {
+	n := uint64(d)
+	if d < latencySubBuckets {
+		return int(max(d, 0))
+	}
+
+	// the 2 bits after the leading one pick the sub-bucket
+	e := bits.Len64(n) - 1
+	return (e-1)*latencySubBuckets + int(n>>(e-2)&(latencySubBuckets-1))
+}
+
+// latencyBucketMax returns the largest latency in bucket i
+func latencyBucketMax(i int) time.Duration {
+	if i < latencySubBuckets {
+		return time.Duration(i)
+	}
+
+	e, sub := i/latencySubBuckets+1, i%latencySubBuckets
+	return time.Duration((latencySubBuckets+sub+1)<<(e-2) - 1)
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	atomic.AddUint64(&h.counts[latencyBucket(d)], 1)
+}
+
+func (h *latencyHistogram) summary() LatencySummary {
+	var counts [len(h.counts)]uint64
+	var s LatencySummary
+	for i := range counts {
+		counts[i] = atomic.LoadUint64(&h.counts[i])
+		s.Count += counts[i]
+	}
+
+	quantile := func(q float64) time.Duration {
+		target := uint64(q*float64(s.Count)) + 1
+		seen := uint64(0)
+		for i, n := range counts {
+			if seen += n; seen >= target {
+				return latencyBucketMax(i)
+			}
+		}
+		return 0
+	}
+
+	if s.Count > 0 {
+		s.P50, s.P95, s.P99 = quantile(0.5), quantile(0.95), quantile(0.99)
+	}
+	return s
+}
+
+// Latencies returns the latencies tracked since Init, which are all zero
+// unless TrackLatency is set. The time waiting for the lock is included,
+// so growing latencies point to contention.
+func (p *Cache) Latencies() Latencies {
+	if p.latency == nil {
+		return Latencies{}
+	}
+
+	return Latencies{Get: p.latency.get.summary(),
+		Put: p.latency.put.summary(), Load: p.latency.load.summary()}
+}
+
+// observe records the latency of an operation started at start
+func (p *Cache) observe(h *latencyHistogram, start time.Time) {
+	h.record(time.Since(start))
+}
+
+// load calls Loader, tracking its latency
+func (p *Cache) load(ctx context.Context, key string) (interface{}, error) {
+	if p.latency != nil {
+		defer p.observe(&p.latency.load, time.Now())
+	}
+	return p.Loader(ctx, key)
+}
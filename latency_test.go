@@ -0,0 +1,55 @@
+package expiringcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyBucket(t *testing.T) {
+	for _, d := range []time.Duration{0, 1, 3, 4, 5, 7, 8, 100, 1000,
+		time.Millisecond, 3 * time.Second} {
+		b := latencyBucket(d)
+		if upper := latencyBucketMax(b); upper < d || float64(upper) > float64(d)*1.25+1 {
+			t.Errorf("%v falls in bucket %d up to %v", d, b, upper)
+		}
+		if b > 0 && latencyBucketMax(b-1) >= d {
+			t.Errorf("%v also fits bucket %d", d, b-1)
+		}
+	}
+}
+
+func TestLatencies(t *testing.T) {
+	cache := Cache{Duration: 60, TrackLatency: true,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			time.Sleep(10 * time.Millisecond)
+			return 1, nil
+		}}
+	cache.Init()
+
+	for i := 0; i < 100; i++ {
+		cache.Put(Key(i), i)
+		cache.Get(Key(i))
+	}
+	cache.GetCtx(context.Background(), "missing")
+
+	l := cache.Latencies()
+	if l.Get.Count != 101 || l.Put.Count != 101 || l.Load.Count != 1 {
+		t.Errorf("Unexpected counts %d, %d, %d", l.Get.Count, l.Put.Count,
+			l.Load.Count)
+	}
+	if l.Load.P50 < 10*time.Millisecond || l.Load.P99 != l.Load.P50 {
+		t.Errorf("Load latency %v, %v", l.Load.P50, l.Load.P99)
+	}
+	if l.Get.P50 == 0 || l.Get.P50 > l.Get.P95 || l.Get.P95 > l.Get.P99 {
+		t.Errorf("Get latency percentiles %v, %v, %v", l.Get.P50, l.Get.P95,
+			l.Get.P99)
+	}
+
+	untracked := Cache{}
+	untracked.Init()
+	untracked.Get("a")
+	if l := untracked.Latencies(); l.Get.Count != 0 {
+		t.Errorf("Latency tracked without TrackLatency")
+	}
+}
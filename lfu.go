@@ -0,0 +1,87 @@
+package expiringcache
+
+import (
+	"container/heap"
+)
+
+// LFU is an EvictionPolicy evicting the least frequently used key, fetched
+// or written the fewest times since it was added, and of those the one
+// used longest ago
+type LFU struct {
+	heap  lfuHeap
+	elems map[string]*lfuEntry
+	clock uint64 // orders uses
+}
+
+type lfuEntry struct {
+	cv    *CacheValue
+	uses  uint64
+	last  uint64 // clock at the last use
+	index int    // in the heap
+}
+
+func NewLFU() *LFU {
+	return &LFU{elems: make(map[string]*lfuEntry)}
+}
+
+func (p *LFU) OnAdd(cv *CacheValue) {
+	p.clock++
+	e := &lfuEntry{cv: cv, last: p.clock}
+	p.elems[cv.Key] = e
+	heap.Push(&p.heap, e)
+}
+
+func (p *LFU) OnAccess(cv *CacheValue) {
+	if e, ok := p.elems[cv.Key]; ok {
+		p.clock++
+		e.uses++
+		e.last = p.clock
+		heap.Fix(&p.heap, e.index)
+	}
+}
+
+func (p *LFU) OnRemove(cv *CacheValue, typ EventType) {
+	if e, ok := p.elems[cv.Key]; ok {
+		heap.Remove(&p.heap, e.index)
+		delete(p.elems, cv.Key)
+	}
+}
+
+func (p *LFU) Victim() *CacheValue {
+	if len(p.heap) == 0 {
+		return nil
+	}
+	return p.heap[0].cv
+}
+
+// lfuHeap keeps the entry to evict first on top
+type lfuHeap []*lfuEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].uses != h[j].uses {
+		return h[i].uses < h[j].uses
+	}
+	return h[i].last < h[j].last
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x interface{}) {
+	e := x.(*lfuEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,30 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestLFU(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 3, NEvictions: 1,
+		EvictionPolicy: NewLFU()}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Put("c", 3)
+	cache.Get("a")
+	cache.Get("a")
+	cache.Get("b")
+	cache.Get("c")
+	cache.Put("d", 4)
+
+	// b and c were used as often, b longest ago
+	if !cache.Exists("a") || cache.Exists("b") || !cache.Exists("c") {
+		t.Errorf("Least frequently used key not evicted")
+	}
+
+	cache.Del("a")
+	if v := cache.EvictionPolicy.Victim(); v == nil || v.Key != "d" {
+		t.Errorf("Victim is %v, expected d", v)
+	}
+}
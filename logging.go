@@ -0,0 +1,50 @@
+package expiringcache
+
+// Logger receives notable events of the cache: summaries of the periodic
+// eviction, eviction storms, failed refreshes and failures to persist the
+// cache. args alternate keys and values as with log/slog, whose *Logger
+// can be used as is.
+type Logger interface {
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, args ...interface{})  {}
+func (nopLogger) Warn(msg string, args ...interface{})  {}
+func (nopLogger) Error(msg string, args ...interface{}) {}
+
+func (p *Cache) logger() Logger {
+	if p.Logger == nil {
+		return nopLogger{}
+	}
+	return p.Logger
+}
+
+// logEvictions logs a summary of the keys removed by one round of the
+// periodic eviction, warning if more than half the cache was evicted
+func (p *Cache) logEvictions(expired int) {
+	p.Lock()
+	evicted, keys := p.evicted, p.data.Len()
+	p.evicted = 0
+	limit := p.Max
+	p.Unlock()
+
+	if expired == 0 && evicted == 0 {
+		return
+	}
+
+	if p.HighWatermark > 0 {
+		limit = p.HighWatermark
+	}
+
+	if limit > 0 && evicted > limit/2 {
+		p.logger().Warn("expiringcache: eviction storm", "evicted", evicted,
+			"limit", limit, "interval", p.sweepInterval())
+	}
+
+	p.logger().Info("expiringcache: periodic eviction", "expired", expired,
+		"evicted", evicted, "keys", keys)
+}
@@ -0,0 +1,33 @@
+package expiringcache
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	cache := Cache{Duration: 60, Max: 10, NEvictions: 10,
+		Logger: slog.New(slog.NewTextHandler(&buf, nil))}
+	cache.Init()
+
+	for i := 0; i < 11; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+	cache.logEvictions(2)
+
+	out := buf.String()
+	if !strings.Contains(out, "eviction storm") ||
+		!strings.Contains(out, "expired=2 evicted=10 keys=1") {
+		t.Errorf("Unexpected log:\n%s", out)
+	}
+
+	buf.Reset()
+	cache.logEvictions(0)
+	if buf.Len() != 0 {
+		t.Errorf("Logged a summary without evictions:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,40 @@
+package expiringcache
+
+import (
+	"container/list"
+)
+
+// LRU is an EvictionPolicy evicting the least recently used key, fetched
+// or written longest ago
+type LRU struct {
+	order *list.List
+	elems map[string]*list.Element
+}
+
+func NewLRU() *LRU {
+	return &LRU{order: list.New(), elems: make(map[string]*list.Element)}
+}
+
+func (p *LRU) OnAdd(cv *CacheValue) {
+	p.elems[cv.Key] = p.order.PushFront(cv)
+}
+
+func (p *LRU) OnAccess(cv *CacheValue) {
+	if e, ok := p.elems[cv.Key]; ok {
+		p.order.MoveToFront(e)
+	}
+}
+
+func (p *LRU) OnRemove(cv *CacheValue, typ EventType) {
+	if e, ok := p.elems[cv.Key]; ok {
+		p.order.Remove(e)
+		delete(p.elems, cv.Key)
+	}
+}
+
+func (p *LRU) Victim() *CacheValue {
+	if e := p.order.Back(); e != nil {
+		return e.Value.(*CacheValue)
+	}
+	return nil
+}
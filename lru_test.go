@@ -0,0 +1,73 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestLRU(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 3, NEvictions: 1,
+		EvictionPolicy: NewLRU()}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+	cache.Put("c", 3)
+	cache.Get("a")
+	cache.Put("d", 4)
+
+	if !cache.Exists("a") || cache.Exists("b") {
+		t.Errorf("Least recently used key not evicted")
+	}
+}
+
+// byPriority evicts the lowest priority key, as a policy of one's own
+type byPriority struct {
+	entries map[string]*CacheValue
+}
+
+func (p *byPriority) OnAdd(cv *CacheValue) {
+	p.entries[cv.Key] = cv
+}
+
+func (p *byPriority) OnAccess(cv *CacheValue) {}
+
+func (p *byPriority) OnRemove(cv *CacheValue, typ EventType) {
+	delete(p.entries, cv.Key)
+}
+
+func (p *byPriority) Victim() *CacheValue {
+	var min_v *CacheValue
+	for _, cv := range p.entries {
+		if min_v == nil || cv.Priority < min_v.Priority {
+			min_v = cv
+		}
+	}
+	return min_v
+}
+
+func TestEvictionPolicy(t *testing.T) {
+	policy := &byPriority{entries: make(map[string]*CacheValue)}
+	cache := Cache{Duration: 60, Max: 3, NEvictions: 1,
+		EvictionPolicy: policy}
+	cache.Init()
+
+	cache.PutWithPriority("a", 1, 60, 3)
+	cache.PutWithPriority("b", 2, 60, 1)
+	cache.PutWithPriority("c", 3, 60, 2)
+	cache.PutWithPriority("d", 4, 60, 5)
+
+	if cache.Exists("b") || !cache.Exists("c") {
+		t.Errorf("Custom policy not used")
+	}
+	if len(policy.entries) != 3 {
+		t.Errorf("Policy tracks %d keys, expected 3", len(policy.entries))
+	}
+
+	// Reconfigure keeps the policy and what it tracks
+	s := cache.Settings()
+	s.Max = 10
+	cache.Reconfigure(s)
+	if len(policy.entries) != 3 {
+		t.Errorf("Policy tracks %d keys after Reconfigure", len(policy.entries))
+	}
+}
@@ -0,0 +1,227 @@
+package expiringcache
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Manager keeps a group of named caches within a shared budget, for
+// applications with several caches that must together stay within their
+// memory. When the caches hold more keys or cost more than allowed, each
+// is shrunk to a share of the budget in proportion to its size, weighted
+// by its hits per key since keys were last evicted, so the least valuable
+// caches give up the most.
+//
+// The budget is enforced in the background shortly after keys are added,
+// so it may be exceeded briefly.
+type Manager struct {
+	MaxEntries int   // keys across all caches, 0 for no limit
+	MaxCost    int64 // cost (see Cache.CostFunc) across all caches, 0 for no limit
+
+	caches map[string]*Cache
+	hits   map[*Cache]uint64 // hit counts when keys were last evicted
+	wake   chan struct{}
+	stop   chan struct{}
+	sync.Mutex
+}
+
+func (m *Manager) Init() {
+	m.caches = make(map[string]*Cache)
+	m.hits = make(map[*Cache]uint64)
+	m.wake = make(chan struct{}, 1)
+	m.stop = make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-m.wake:
+				m.Enforce()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Add puts cache, which must be initialized, under the budget as name
+func (m *Manager) Add(name string, cache *Cache) {
+	cache.Lock()
+	cache.group = m
+	cache.Unlock()
+
+	m.Lock()
+	m.caches[name] = cache
+	m.Unlock()
+
+	m.notify()
+}
+
+// Cache returns the cache added as name, or nil
+func (m *Manager) Cache(name string) *Cache {
+	m.Lock()
+	defer m.Unlock()
+	return m.caches[name]
+}
+
+// Remove takes the cache added as name out of the budget
+func (m *Manager) Remove(name string) {
+	m.Lock()
+	cache := m.caches[name]
+	delete(m.caches, name)
+	delete(m.hits, cache)
+	m.Unlock()
+
+	if cache != nil {
+		cache.Lock()
+		cache.group = nil
+		cache.Unlock()
+	}
+}
+
+// Close stops enforcing the budget in the background
+func (m *Manager) Close() {
+	close(m.stop)
+}
+
+// notify asks for the budget to be enforced without waiting for it. It is
+// called by caches with their lock held, so it must not lock them.
+func (m *Manager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// managedCache is a cache with what it counts against the budget
+type managedCache struct {
+	cache  *Cache
+	keys   int
+	cost   int64
+	hits   uint64
+	recent float64 // hits per key since keys were last evicted
+}
+
+// Enforce evicts keys until the caches are within the budget and returns
+// the number of keys evicted
+func (m *Manager) Enforce() int {
+	m.Lock()
+	defer m.Unlock()
+
+	var caches []*managedCache
+	var keys int
+	var cost int64
+	for _, c := range m.caches {
+		c.Lock()
+		mc := &managedCache{cache: c, keys: c.data.Len(), cost: c.cost}
+		c.Unlock()
+
+		mc.hits = c.hitCount()
+		mc.recent = float64(mc.hits-m.hits[c]) / float64(max(mc.keys, 1))
+
+		caches = append(caches, mc)
+		keys += mc.keys
+		cost += mc.cost
+	}
+
+	maxKeys := unlimited(len(caches))
+	if m.MaxEntries > 0 && keys > m.MaxEntries {
+		maxKeys = shares(caches, float64(m.MaxEntries),
+			func(mc *managedCache) float64 { return float64(mc.keys) })
+	}
+	maxCost := unlimited(len(caches))
+	if m.MaxCost > 0 && cost > m.MaxCost {
+		maxCost = shares(caches, float64(m.MaxCost),
+			func(mc *managedCache) float64 { return float64(mc.cost) })
+	}
+
+	evicted := 0
+	for i, mc := range caches {
+		if float64(mc.keys) <= maxKeys[i] && float64(mc.cost) <= maxCost[i] {
+			continue
+		}
+
+		// evicting a key may remove others with it, e.g. its dependents,
+		// so check the cache itself rather than count evictions
+		c := mc.cache
+		c.Lock()
+		n := c.data.Len()
+		for c.data.Len() > 0 && (float64(c.data.Len()) > maxKeys[i] ||
+			float64(c.cost) > maxCost[i]) {
+			if !c.evictKey() {
+				break
+			}
+		}
+		evicted += n - c.data.Len()
+		c.Unlock()
+
+		m.hits[c] = mc.hits
+	}
+
+	return evicted
+}
+
+// shares splits budget between caches in proportion to their sizes, as
+// returned by size, weighted by their recent hits. Caches smaller than
+// their share keep what they have and the rest of the budget is split
+// again between the others.
+func shares(caches []*managedCache, budget float64,
+	size func(mc *managedCache) float64) []float64 {
+	limits := make([]float64, len(caches))
+	open := make([]bool, len(caches))
+	for i := range open {
+		open[i] = true
+	}
+
+	for {
+		var total float64
+		for i, mc := range caches {
+			if open[i] {
+				total += size(mc) * (1 + mc.recent)
+			}
+		}
+
+		// a cache that fits its share only leaves more for the others, so
+		// all those that fit can be settled at once
+		settled := false
+		left := budget
+		for i, mc := range caches {
+			if !open[i] {
+				continue
+			}
+
+			s := size(mc)
+			if total == 0 || s <= budget*s*(1+mc.recent)/total {
+				limits[i] = s
+				left -= s
+				open[i] = false
+				settled = true
+			}
+		}
+		budget = left
+
+		if !settled {
+			for i, mc := range caches {
+				if open[i] {
+					limits[i] = math.Floor(budget * size(mc) * (1 + mc.recent) /
+						total)
+				}
+			}
+			return limits
+		}
+	}
+}
+
+// unlimited returns limits for n caches that are never exceeded
+func unlimited(n int) []float64 {
+	limits := make([]float64, n)
+	for i := range limits {
+		limits[i] = math.Inf(1)
+	}
+	return limits
+}
+
+// hitCount returns the number of times a key was found in the cache
+func (p *Cache) hitCount() uint64 {
+	return atomic.LoadUint64(&p.hits)
+}
@@ -0,0 +1,123 @@
+package expiringcache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	m := Manager{MaxEntries: 10}
+	m.Init()
+	defer m.Close()
+
+	hot := &Cache{Duration: 60}
+	hot.Init()
+	cold := &Cache{Duration: 60}
+	cold.Init()
+
+	m.Add("hot", hot)
+	m.Add("cold", cold)
+	if m.Cache("hot") != hot {
+		t.Errorf("Cache returned the wrong cache")
+	}
+
+	for i := 0; i < 6; i++ {
+		hot.Put(fmt.Sprint(i), i)
+		hot.Get(fmt.Sprint(i))
+	}
+	for i := 0; i < 6; i++ {
+		cold.Put(fmt.Sprint(i), i)
+	}
+
+	m.Enforce()
+
+	if hot.Count()+cold.Count() > 10 {
+		t.Errorf("Manager kept %d keys, expected at most 10",
+			hot.Count()+cold.Count())
+	}
+	if hot.Count() != 6 || cold.Count() != 4 {
+		t.Errorf("Expected evictions from the cold cache, got %d hot and %d cold keys",
+			hot.Count(), cold.Count())
+	}
+
+	m.Remove("cold")
+	for i := 6; i < 12; i++ {
+		cold.Put(fmt.Sprint(i), i)
+	}
+	if m.Enforce() != 0 {
+		t.Errorf("Removed cache counted against the budget")
+	}
+}
+
+func TestManagerCost(t *testing.T) {
+	m := Manager{MaxCost: 100}
+	m.Init()
+	defer m.Close()
+
+	cache := &Cache{Duration: 60, CostFunc: func(key string, value interface{}) int64 {
+		return 30
+	}}
+	cache.Init()
+	m.Add("a", cache)
+
+	for i := 0; i < 5; i++ {
+		cache.Put(fmt.Sprint(i), i)
+	}
+	m.Enforce()
+
+	if cache.Cost() > 100 {
+		t.Errorf("Manager kept a cost of %d, expected at most 100", cache.Cost())
+	}
+}
+
+func TestManagerShares(t *testing.T) {
+	m := Manager{MaxEntries: 12}
+	m.Init()
+	defer m.Close()
+
+	// equally valuable caches shrink in proportion to their size
+	small := &Cache{Duration: 60}
+	small.Init()
+	large := &Cache{Duration: 60}
+	large.Init()
+
+	// filled before they are managed so the budget is enforced once
+	for i := 0; i < 6; i++ {
+		small.Put(fmt.Sprint(i), i)
+	}
+	for i := 0; i < 12; i++ {
+		large.Put(fmt.Sprint(i), i)
+	}
+	m.Add("small", small)
+	m.Add("large", large)
+
+	m.Enforce()
+	if small.Count() != 4 || large.Count() != 8 {
+		t.Errorf("Left %d small and %d large keys", small.Count(),
+			large.Count())
+	}
+}
+
+func TestManagerDependents(t *testing.T) {
+	m := Manager{MaxEntries: 8}
+	m.Init()
+	defer m.Close()
+
+	cache := &Cache{Duration: 60, EvictionPolicy: NewLRU()}
+	cache.Init()
+
+	// evicting the least recently used key removes its dependents too
+	cache.Put("base", 0)
+	for i := 0; i < 4; i++ {
+		cache.PutWithDeps(fmt.Sprint(i), i, 60, "base")
+	}
+	for i := 4; i < 9; i++ {
+		cache.Put(fmt.Sprint(i), i)
+	}
+	m.Add("a", cache)
+
+	m.Enforce()
+	if cache.Count() != 5 {
+		t.Errorf("Left %d keys, expected 5", cache.Count())
+	}
+}
@@ -0,0 +1,47 @@
+package expiringcache
+
+// PutWithMetadata is like Put but also attaches metadata to the key, e.g.
+// a trace ID or where the value came from, without wrapping the value. The
+// metadata is returned by Metadata and GetEntry and is replaced by the next
+// Put of the key.
+func (p *Cache) PutWithMetadata(key string, value interface{},
+	metadata map[string]string) error {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	o := p.defaultOptions(key, value)
+	o.metadata = copyMetadata(metadata)
+	_, _, err = p.put(key, value, o)
+	p.Unlock()
+	return err
+}
+
+// Metadata returns a copy of the metadata of key, and false if it is not in
+// the cache
+func (p *Cache) Metadata(key string) (map[string]string, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	v := p.find(p.canonicalKey(key))
+	if v == nil {
+		return nil, false
+	}
+
+	return copyMetadata(v.Metadata), true
+}
+
+// copyMetadata copies m so the caller and the cache don't share it
+func copyMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
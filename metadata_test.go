@@ -0,0 +1,37 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	meta := map[string]string{"source": "db"}
+	cache.PutWithMetadata("a", 1, meta)
+	meta["source"] = "changed"
+
+	if m, ok := cache.Metadata("a"); !ok || m["source"] != "db" {
+		t.Errorf("Metadata returned %v, %v", m, ok)
+	}
+
+	cv, _ := cache.GetEntry("a")
+	cv.Metadata["source"] = "changed"
+	if m, _ := cache.Metadata("a"); m["source"] != "db" {
+		t.Errorf("Metadata shared with GetEntry caller")
+	}
+
+	cache.UpdateWithExpiry("a", 120, func(old interface{}, ok bool) (
+		interface{}, bool) {
+		return 2, true
+	})
+	if m, _ := cache.Metadata("a"); m["source"] != "db" {
+		t.Errorf("Metadata lost on update")
+	}
+
+	cache.Put("a", 3)
+	if m, ok := cache.Metadata("a"); !ok || m != nil {
+		t.Errorf("Put did not replace metadata: %v", m)
+	}
+}
@@ -0,0 +1,72 @@
+package expiringcache
+
+// Handler is a step that Get and Put pass through, see Use
+type Handler struct {
+	// Get returns the value of key and whether it was found
+	Get func(key string) (interface{}, bool)
+	// Put stores value for key
+	Put func(key string, value interface{}) error
+}
+
+// Middleware wraps the next Handler, e.g. to encrypt or validate values on
+// Put and decrypt them on Get, or to count calls. It returns next with the
+// functions it wraps replaced.
+type Middleware func(next Handler) Handler
+
+// Use adds middleware that Get, Lookup, Put and PutWithExpiry (and
+// PutWithPriority and the Set variants of each) pass through, in the order
+// added: the first one sees calls first and their results last. Other
+// operations, such as GetEntry or Iter, see the values as stored. Like the other settings, Use must be
+// called before the cache is shared between goroutines.
+func (p *Cache) Use(mw ...Middleware) {
+	p.middleware = append(p.middleware, mw...)
+}
+
+// chain wraps h in the middleware
+func (p *Cache) chain(h Handler) Handler {
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		h = p.middleware[i](h)
+	}
+	return h
+}
+
+// lookup is Lookup without the middleware
+func (p *Cache) lookup(key string) (interface{}, bool) {
+	if p.absent(key) {
+		return nil, false
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	return p.get(key)
+}
+
+// putDefault is Put without the middleware
+func (p *Cache) putDefault(key string, value interface{}) error {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	if err = p.waitForRoom(key); err == nil {
+		_, _, err = p.put(key, value, p.defaultOptions(key, value))
+	}
+	p.Unlock()
+	return err
+}
+
+// putWith is PutWithPriority without the middleware
+func (p *Cache) putWith(key string, value interface{}, o entryOptions) error {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	if err = p.waitForRoom(key); err == nil {
+		_, _, err = p.put(key, value, o)
+	}
+	p.Unlock()
+	return err
+}
@@ -0,0 +1,62 @@
+package expiringcache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUse(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			get := next.Get
+			next.Get = func(key string) (interface{}, bool) {
+				calls = append(calls, name)
+				return get(key)
+			}
+			return next
+		}
+	}
+
+	// store strings upper case, returning them lower case
+	cache.Use(trace("first"), trace("second"), func(next Handler) Handler {
+		get, put := next.Get, next.Put
+		next.Get = func(key string) (interface{}, bool) {
+			v, ok := get(key)
+			if s, isString := v.(string); isString {
+				v = strings.ToLower(s)
+			}
+			return v, ok
+		}
+		next.Put = func(key string, value interface{}) error {
+			s, ok := value.(string)
+			if !ok {
+				return errors.New("not a string")
+			}
+			return put(key, strings.ToUpper(s))
+		}
+		return next
+	})
+
+	if err := cache.Set("a", 1); err == nil {
+		t.Errorf("Put did not pass through middleware")
+	}
+	cache.PutWithExpiry("a", "value", 10)
+	if v := cache.Get("a"); v != "value" {
+		t.Errorf("Get returned %v", v)
+	}
+	if cv, _ := cache.GetEntry("a"); cv.Value != "VALUE" {
+		t.Errorf("Stored value is %v", cv.Value)
+	}
+	if d, _ := cache.TTL("a"); d <= 9*time.Second || d > 10*time.Second {
+		t.Errorf("PutWithExpiry through middleware lost the TTL: %v", d)
+	}
+	if strings.Join(calls, ",") != "first,second" {
+		t.Errorf("Middleware called in order %v", calls)
+	}
+}
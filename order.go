@@ -0,0 +1,31 @@
+package expiringcache
+
+import (
+	"sort"
+)
+
+// Order of the entries yielded by IterOrdered
+type Order int
+
+const (
+	ByKey    Order = iota // ascending by key
+	ByExpiry              // soonest first, keys that never expire last
+)
+
+// IterOrdered is like IterSnapshot but promises to yield the entries in
+// order, so that output such as an exported snapshot is reproducible.
+// Entries that expire at the same time are yielded by key.
+func (p *Cache) IterOrdered(order Order) <-chan *CacheValue {
+	p.Lock()
+	entries := p.entries()
+	p.Unlock()
+
+	// entries come by key, which a stable sort keeps for ties
+	if order == ByExpiry {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].expiry() < entries[j].expiry()
+		})
+	}
+
+	return p.yieldEntries(entries)
+}
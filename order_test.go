@@ -0,0 +1,33 @@
+package expiringcache
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIterOrdered(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.PutWithExpiry("d", 1, 30)
+	cache.PutWithExpiry("b", 2, 0)
+	cache.PutWithExpiry("a", 3, 60)
+	cache.PutWithExpiry("c", 4, 30)
+
+	for _, test := range []struct {
+		order    Order
+		expected string
+	}{
+		{ByKey, "abcd"},
+		{ByExpiry, "cdab"},
+	} {
+		var keys []string
+		for cv := range cache.IterOrdered(test.order) {
+			keys = append(keys, cv.Key)
+		}
+		if s := strings.Join(keys, ""); s != test.expected {
+			t.Errorf("Order %d yielded %s, expected %s", test.order, s,
+				test.expected)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package expiringcache
+
+import (
+	"context"
+	"time"
+)
+
+// OriginKey is the metadata key holding the origin of an entry, see
+// WithOrigin
+const OriginKey = "origin"
+
+type originContextKey struct{}
+
+// WithOrigin returns a context labelling the writes made with it by PutCtx
+// and PutWithExpiryCtx with origin, e.g. the name of the component making
+// them, so teams sharing a cache can tell who last wrote a key. The origin
+// replaces the metadata of the entry, under OriginKey, and is shown in
+// events and Dump.
+func WithOrigin(ctx context.Context, origin string) context.Context {
+	return context.WithValue(ctx, originContextKey{}, origin)
+}
+
+// originOf returns the origin set on ctx with WithOrigin, if any
+func originOf(ctx context.Context) string {
+	origin, _ := ctx.Value(originContextKey{}).(string)
+	return origin
+}
+
+// putFrom stores key with the options returned by options, labelled with
+// origin
+func (p *Cache) putFrom(origin, key string, value interface{},
+	options func(key string, value interface{}) entryOptions) error {
+	if p.latency != nil {
+		defer p.observe(&p.latency.put, time.Now())
+	}
+
+	put := func(key string, value interface{}) error {
+		value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+		if err != nil {
+			return err
+		}
+
+		p.Lock()
+		defer p.Unlock()
+
+		if err := p.waitForRoom(key); err != nil {
+			return err
+		}
+		o := options(key, value)
+		o.metadata = map[string]string{OriginKey: origin}
+		_, _, err = p.put(key, value, o)
+		return err
+	}
+
+	if len(p.middleware) > 0 {
+		return p.chain(Handler{Get: p.lookup, Put: put}).Put(key, value)
+	}
+	return put(key, value)
+}
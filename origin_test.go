@@ -0,0 +1,42 @@
+package expiringcache
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithOrigin(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	events, unsubscribe := cache.Subscribe()
+	defer unsubscribe()
+
+	ctx := WithOrigin(context.Background(), "billing")
+	cache.PutCtx(ctx, "a", 1)
+	cache.PutWithExpiryCtx(ctx, "b", 2, 10)
+
+	if m, _ := cache.Metadata("a"); m[OriginKey] != "billing" {
+		t.Errorf("Origin not recorded: %v", m)
+	}
+	if d, _ := cache.TTL("b"); d <= 9*time.Second || d > 10*time.Second {
+		t.Errorf("PutWithExpiryCtx lost the TTL: %v", d)
+	}
+	if e := <-events; e.Origin != "billing" {
+		t.Errorf("Event has origin %q", e.Origin)
+	}
+
+	var buf bytes.Buffer
+	cache.Dump(&buf, FormatJSON)
+	if !strings.Contains(buf.String(), `"origin":"billing"`) {
+		t.Errorf("Dump lacks the origin: %s", buf.String())
+	}
+
+	cache.PutCtx(context.Background(), "a", 3)
+	if m, _ := cache.Metadata("a"); m[OriginKey] != "" {
+		t.Errorf("Origin kept after a write without one: %v", m)
+	}
+}
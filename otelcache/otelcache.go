@@ -0,0 +1,53 @@
+// Package otelcache records the operations of an expiringcache.Cache as
+// OpenTelemetry spans. It is a module of its own so that expiringcache
+// itself doesn't depend on OpenTelemetry.
+package otelcache
+
+import (
+	"context"
+	"fmt"
+	"github.com/deep-compute/expiringcache"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/deep-compute/expiringcache"
+
+// Tracer is an expiringcache.Tracer creating a span named
+// "expiringcache.<op>" for each operation. Spans carry a hash of the key
+// rather than the key itself, which may hold personal data, and get spans
+// record whether the key was found.
+//
+//	cache := expiringcache.Cache{Tracer: &otelcache.Tracer{}}
+type Tracer struct {
+	// Tracer creates the spans. Defaults to the tracer of the global
+	// TracerProvider.
+	Tracer trace.Tracer
+}
+
+func (p *Tracer) Start(ctx context.Context, op string,
+	key string) (context.Context, func(hit bool, err error)) {
+	tracer := p.Tracer
+	if tracer == nil {
+		tracer = otel.Tracer(instrumentationName)
+	}
+
+	ctx, span := tracer.Start(ctx, "expiringcache."+op,
+		trace.WithAttributes(attribute.String("cache.key_hash",
+			fmt.Sprintf("%016x", expiringcache.FNVHash(key)))))
+
+	return ctx, func(hit bool, err error) {
+		if op == "get" {
+			span.SetAttributes(attribute.Bool("cache.hit", hit))
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
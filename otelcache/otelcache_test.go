@@ -0,0 +1,50 @@
+package otelcache
+
+import (
+	"context"
+	"errors"
+	"github.com/deep-compute/expiringcache"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	cache := expiringcache.Cache{Duration: 60,
+		Tracer: &Tracer{Tracer: tp.Tracer("test")},
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			return nil, errors.New("down")
+		}}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.GetCtx(context.Background(), "a")
+	cache.GetCtx(context.Background(), "b")
+
+	spans := sr.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Recorded %d spans, expected 3", len(spans))
+	}
+
+	hit := func(s sdktrace.ReadOnlySpan) bool {
+		for _, kv := range s.Attributes() {
+			if kv.Key == "cache.hit" {
+				return kv.Value.AsBool()
+			}
+		}
+		return false
+	}
+
+	if spans[0].Name() != "expiringcache.get" || !hit(spans[0]) ||
+		hit(spans[1]) {
+		t.Errorf("Hit and miss not recorded")
+	}
+
+	if spans[2].Name() != "expiringcache.load" ||
+		spans[2].Status().Description != "down" {
+		t.Errorf("Loader error not recorded on %s", spans[2].Name())
+	}
+}
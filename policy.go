@@ -0,0 +1,67 @@
+package expiringcache
+
+// Policy decides which key is evicted when the cache is full
+type Policy int
+
+const (
+	// Evict the lowest priority, soonest expiring of NSamples random keys,
+	// or of all keys if EvictSoonest is set
+	PolicySampled Policy = iota
+	// Adaptive Replacement Cache, which balances evicting the least
+	// recently and the least frequently used keys depending on which
+	// would have kept more of the keys requested again. Priorities are
+	// ignored.
+	PolicyARC
+	// Segmented LRU: keys start in a probation segment and move to a
+	// protected one, SLRUProtectedRatio of the cache, when fetched or
+	// written again. The least recently used key in probation is evicted
+	// first, so keys used only once don't push out established ones.
+	// Priorities are ignored.
+	PolicySLRU
+	// Evict the key added first. Cheap and predictable; priorities and
+	// accesses are ignored.
+	PolicyFIFO
+	// Evict a key chosen uniformly at random in constant time, using
+	// RandSource if set. Priorities and accesses are ignored.
+	PolicyRandom
+)
+
+// EvictionPolicy tracks the entries of the cache to pick the next one to
+// evict, to plug in a policy other than those of Policy, e.g. one aware of
+// business priorities. Its methods are called with the cache locked, so
+// they must be quick and must not use the cache.
+type EvictionPolicy interface {
+	// OnAdd is called when cv is added to the cache
+	OnAdd(cv *CacheValue)
+	// OnAccess is called when cv is fetched or written again
+	OnAccess(cv *CacheValue)
+	// OnRemove is called when cv leaves the cache, as told by typ
+	OnRemove(cv *CacheValue, typ EventType)
+	// Victim returns the entry to evict next, or nil if there is none
+	Victim() *CacheValue
+}
+
+// newPolicy returns EvictionPolicy or the tracker for Policy, or nil for
+// PolicySampled which needs none
+func (p *Cache) newPolicy() EvictionPolicy {
+	if p.EvictionPolicy != nil {
+		return p.EvictionPolicy
+	}
+
+	capacity := p.Max
+	if p.HighWatermark > 0 {
+		capacity = p.HighWatermark
+	}
+
+	switch p.Policy {
+	case PolicyARC:
+		return newARC(capacity)
+	case PolicySLRU:
+		return newSLRU(capacity, p.SLRUProtectedRatio)
+	case PolicyFIFO:
+		return newFIFO()
+	case PolicyRandom:
+		return newRandom(p.intn)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package expiringcache
+
+// PopOldest removes the key that was added to the cache first and returns
+// it with its value, and false if the cache is empty. It scans every key.
+func (p *Cache) PopOldest() (string, interface{}, bool) {
+	return p.popMin(func(a, b *CacheValue) bool {
+		return a.CreatedAt < b.CreatedAt
+	})
+}
+
+// PopExpiringSoonest removes the key that expires first and returns it with
+// its value, and false if the cache is empty. It scans every key.
+func (p *Cache) PopExpiringSoonest() (string, interface{}, bool) {
+	return p.popMin(func(a, b *CacheValue) bool {
+		return a.expiry() < b.expiry()
+	})
+}
+
+// PeekRandom returns a random key and its value without removing it, and
+// false if the cache is empty
+func (p *Cache) PeekRandom() (string, interface{}, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.data.Len() == 0 {
+		return "", nil, false
+	}
+
+	v := p.data.At(p.intn(p.data.Len())).(*CacheValue)
+	return v.Key, p.clone(v.Value), true
+}
+
+// popMin removes and returns the key that sorts first by less
+func (p *Cache) popMin(less func(a, b *CacheValue) bool) (string,
+	interface{}, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	var min_v *CacheValue
+	for i := 0; i < p.data.Len(); i++ {
+		v := p.data.At(i).(*CacheValue)
+		if min_v == nil || less(v, min_v) {
+			min_v = v
+		}
+	}
+
+	if min_v == nil {
+		return "", nil, false
+	}
+
+	p.remove(min_v, EventDelete)
+	return min_v.Key, p.clone(min_v.Value), true
+}
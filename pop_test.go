@@ -0,0 +1,38 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestPopVariants(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	if _, _, ok := cache.PeekRandom(); ok {
+		t.Errorf("PeekRandom found a key in an empty cache")
+	}
+
+	cache.PutWithExpiry("a", 1, 30)
+	cache.PutWithExpiry("b", 2, 10)
+	cache.PutWithExpiry("c", 3, 0)
+
+	// make "c" the oldest
+	cache.data.Find(&CacheValue{Key: "c"}).(*CacheValue).CreatedAt -= 10
+
+	if k, v, ok := cache.PeekRandom(); !ok || cache.Get(k) != v ||
+		cache.Count() != 3 {
+		t.Errorf("PeekRandom returned %v, %v, %v", k, v, ok)
+	}
+
+	if k, v, ok := cache.PopOldest(); !ok || k != "c" || v != 3 {
+		t.Errorf("PopOldest returned %v, %v, %v", k, v, ok)
+	}
+
+	if k, _, ok := cache.PopExpiringSoonest(); !ok || k != "b" {
+		t.Errorf("PopExpiringSoonest returned %v, %v", k, ok)
+	}
+
+	if cache.Count() != 1 || !cache.Exists("a") {
+		t.Errorf("Pop removed the wrong keys")
+	}
+}
@@ -0,0 +1,66 @@
+package expiringcache
+
+import (
+	"strings"
+)
+
+// tenant tracks the keys under one of the prefixes in Quotas
+type tenant struct {
+	prefix string
+	quota  int
+	keys   map[string]*CacheValue
+}
+
+func (p *Cache) initTenants() {
+	p.tenants = nil
+	for prefix, quota := range p.Quotas {
+		p.tenants = append(p.tenants, &tenant{prefix: prefix, quota: quota,
+			keys: make(map[string]*CacheValue)})
+	}
+}
+
+// tenantOf returns the tenant with the longest prefix of key, if any
+func (p *Cache) tenantOf(key string) *tenant {
+	var t *tenant
+	for _, c := range p.tenants {
+		if strings.HasPrefix(key, c.prefix) &&
+			(t == nil || len(c.prefix) > len(t.prefix)) {
+			t = c
+		}
+	}
+	return t
+}
+
+// evictTenant evicts one of the keys of t, chosen among NSamples of them as
+// evictKey does, or among all of them if EvictSoonest is set
+func (p *Cache) evictTenant(t *tenant) {
+	n := p.NSamples
+	if n == 0 || p.EvictSoonest {
+		n = len(t.keys)
+	}
+
+	var min_v *CacheValue
+	for _, v := range t.keys {
+		if min_v == nil || evictsBefore(v, min_v) {
+			min_v = v
+		}
+
+		if n--; n == 0 {
+			break
+		}
+	}
+
+	if min_v != nil {
+		p.remove(min_v, EventEvict)
+		p.evicted++
+	}
+}
+
+func positiveQuotas(quotas map[string]int) bool {
+	for _, q := range quotas {
+		if q <= 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package expiringcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestQuotas(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 10, NEvictions: 1, EvictSoonest: true,
+		Quotas: map[string]int{"a/": 5, "a/big/": 2}}
+	cache.Init()
+
+	for i := 0; i < 4; i++ {
+		cache.Put("b/"+strconv.Itoa(i), i)
+	}
+
+	// the noisy tenant only evicts its own keys
+	for i := 0; i < 20; i++ {
+		cache.PutWithExpiry("a/"+strconv.Itoa(i), i, 100+i)
+	}
+
+	for i := 0; i < 4; i++ {
+		if !cache.Exists("b/" + strconv.Itoa(i)) {
+			t.Errorf("Key of another tenant evicted")
+		}
+	}
+
+	if cache.Count() != 9 || !cache.Exists("a/19") || cache.Exists("a/14") {
+		t.Errorf("Tenant not limited to its quota, %d keys", cache.Count())
+	}
+
+	// the longest prefix applies
+	for i := 0; i < 3; i++ {
+		cache.PutWithExpiry("a/big/"+strconv.Itoa(i), i, 10+i)
+	}
+	if cache.Exists("a/big/0") || len(cache.tenantOf("a/").keys) != 5 {
+		t.Errorf("Nested prefix not limited to its quota")
+	}
+
+	invalid := Cache{Quotas: map[string]int{"x": 0}}
+	if invalid.Validate() == nil {
+		t.Errorf("Zero quota accepted")
+	}
+}
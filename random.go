@@ -0,0 +1,56 @@
+package expiringcache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Random is an EvictionPolicy evicting a key chosen uniformly at random, as
+// PolicyRandom does. Entries are kept in a slice, moving the last one into
+// the place of a removed one, so every operation takes constant time.
+type Random struct {
+	entries []*CacheValue
+	index   map[string]int
+	intn    func(n int) int
+}
+
+// NewRandom returns a Random policy choosing keys with src, or with a
+// source seeded with the time if src is nil
+func NewRandom(src rand.Source) *Random {
+	if src == nil {
+		src = rand.NewSource(time.Now().UnixNano())
+	}
+	return newRandom(rand.New(src).Intn)
+}
+
+func newRandom(intn func(n int) int) *Random {
+	return &Random{index: make(map[string]int), intn: intn}
+}
+
+func (p *Random) OnAdd(cv *CacheValue) {
+	p.index[cv.Key] = len(p.entries)
+	p.entries = append(p.entries, cv)
+}
+
+func (p *Random) OnAccess(cv *CacheValue) {}
+
+func (p *Random) OnRemove(cv *CacheValue, typ EventType) {
+	i, ok := p.index[cv.Key]
+	if !ok {
+		return
+	}
+
+	last := len(p.entries) - 1
+	p.entries[i] = p.entries[last]
+	p.index[p.entries[i].Key] = i
+	p.entries[last] = nil
+	p.entries = p.entries[:last]
+	delete(p.index, cv.Key)
+}
+
+func (p *Random) Victim() *CacheValue {
+	if len(p.entries) == 0 {
+		return nil
+	}
+	return p.entries[p.intn(len(p.entries))]
+}
@@ -0,0 +1,98 @@
+// Package ratelimit provides per-key rate limiters keeping their counters
+// in an expiringcache.Cache, so idle keys expire and the cache's eviction
+// bounds the memory used.
+package ratelimit
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Limiter reports whether an event for key may happen now
+type Limiter interface {
+	Allow(key string) bool
+}
+
+// FixedWindow allows up to Limit events per key in each window of Window
+// seconds. A Window of 0 or less is a window of one second.
+type FixedWindow struct {
+	Cache  *expiringcache.Cache
+	Limit  int
+	Window int
+}
+
+func (p *FixedWindow) Allow(key string) bool {
+	seconds := p.Window
+	if seconds <= 0 {
+		seconds = 1
+	}
+	window := time.Now().Unix() / int64(seconds)
+	key = key + "@" + strconv.FormatInt(window, 10)
+
+	allowed := false
+	p.Cache.UpdateWithExpiry(key, seconds,
+		func(old interface{}, exists bool) (interface{}, bool) {
+			n := 0
+			if exists {
+				n = old.(int)
+			}
+
+			if n < p.Limit {
+				allowed = true
+				n++
+			}
+			return n, true
+		})
+
+	return allowed
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// TokenBucket allows events per key at a sustained Rate per second with
+// bursts of up to Burst events. With a Rate of 0 or less buckets never
+// refill, so each key is allowed Burst events until it is evicted.
+type TokenBucket struct {
+	Cache *expiringcache.Cache
+	Rate  float64
+	Burst int
+}
+
+func (p *TokenBucket) Allow(key string) bool {
+	now := time.Now()
+
+	// once the bucket has refilled the key is no different from a new one,
+	// a bucket that takes too long to refill is kept until evicted
+	refill := 0
+	if seconds := math.Ceil(float64(p.Burst) / p.Rate); p.Rate > 0 &&
+		seconds <= math.MaxInt32 {
+		refill = int(seconds)
+	}
+
+	allowed := false
+	p.Cache.UpdateWithExpiry(key, refill,
+		func(old interface{}, exists bool) (interface{}, bool) {
+			b := bucket{tokens: float64(p.Burst), last: now}
+			if exists {
+				b = old.(bucket)
+				b.tokens += now.Sub(b.last).Seconds() * math.Max(p.Rate, 0)
+				if b.tokens > float64(p.Burst) {
+					b.tokens = float64(p.Burst)
+				}
+				b.last = now
+			}
+
+			if b.tokens >= 1 {
+				allowed = true
+				b.tokens--
+			}
+			return b, true
+		})
+
+	return allowed
+}
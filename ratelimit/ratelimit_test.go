@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"testing"
+)
+
+func TestLimiters(t *testing.T) {
+	cache := expiringcache.Cache{Duration: 60}
+	cache.Init()
+
+	limiters := map[string]Limiter{
+		"fixed window": &FixedWindow{Cache: &cache, Limit: 3, Window: 3600},
+		"token bucket": &TokenBucket{Cache: &cache, Rate: 0.001, Burst: 3},
+	}
+
+	for name, l := range limiters {
+		allowed := 0
+		for i := 0; i < 5; i++ {
+			if l.Allow(name + "a") {
+				allowed++
+			}
+		}
+
+		if allowed != 3 {
+			t.Errorf("%s allowed %d events, expected 3", name, allowed)
+		}
+
+		if !l.Allow(name + "b") {
+			t.Errorf("%s limited an unrelated key", name)
+		}
+	}
+}
+
+func TestLimitersUnset(t *testing.T) {
+	cache := expiringcache.Cache{}
+	cache.Init()
+
+	fixed := &FixedWindow{Cache: &cache, Limit: 1}
+	if !fixed.Allow("a") {
+		t.Errorf("Fixed window with no Window limited the first event")
+	}
+
+	bucket := &TokenBucket{Cache: &cache, Burst: 2}
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if bucket.Allow("b") {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("Token bucket with no Rate allowed %d events, expected 2",
+			allowed)
+	}
+	if ttl, ok := cache.TTL("b"); !ok || ttl != expiringcache.NoExpiry {
+		t.Errorf("Bucket that never refills stored for %v", ttl)
+	}
+}
@@ -0,0 +1,26 @@
+package expiringcache
+
+// ReadOnlyCache gives access to a Cache without allowing changes to it
+type ReadOnlyCache interface {
+	Get(key string) interface{}
+	Exists(key string) bool
+	Count() int
+	Iter() <-chan *CacheValue
+}
+
+// readOnly wraps a Cache so that it can't be asserted back to one
+type readOnly struct {
+	cache *Cache
+}
+
+// ReadOnly returns a view of the cache for components which must not
+// change it. Values themselves are shared unless CloneFunc or Codec is
+// set, so mutable values can still be modified through it.
+func (p *Cache) ReadOnly() ReadOnlyCache {
+	return readOnly{p}
+}
+
+func (p readOnly) Get(key string) interface{} { return p.cache.Get(key) }
+func (p readOnly) Exists(key string) bool     { return p.cache.Exists(key) }
+func (p readOnly) Count() int                 { return p.cache.Count() }
+func (p readOnly) Iter() <-chan *CacheValue   { return p.cache.Iter() }
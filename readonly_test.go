@@ -0,0 +1,29 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestReadOnly(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+	cache.Put("a", 1)
+
+	ro := cache.ReadOnly()
+	if ro.Get("a") != 1 || !ro.Exists("a") || ro.Count() != 1 {
+		t.Errorf("Read-only view does not reflect the cache")
+	}
+
+	if _, ok := ro.(*Cache); ok {
+		t.Errorf("Read-only view can be asserted to a Cache")
+	}
+
+	cache.Put("b", 2)
+	n := 0
+	for range ro.Iter() {
+		n++
+	}
+	if n != 2 {
+		t.Errorf("Iter yielded %d entries, expected 2", n)
+	}
+}
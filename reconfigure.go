@@ -0,0 +1,84 @@
+package expiringcache
+
+import "sort"
+
+// Settings are the parts of the configuration of a Cache that can be
+// changed while it is in use with Reconfigure. They mean the same as the
+// Cache fields of the same name.
+type Settings struct {
+	Max        int
+	NEvictions int
+	Duration   int
+	Policy     Policy
+}
+
+// Settings returns the current settings, e.g. to change some of them with
+// Reconfigure
+func (p *Cache) Settings() Settings {
+	p.Lock()
+	defer p.Unlock()
+
+	return Settings{Max: p.Max, NEvictions: p.NEvictions,
+		Duration: p.Duration, Policy: p.Policy}
+}
+
+// Reconfigure replaces the settings of the cache while it is in use, e.g.
+// from a dynamic configuration system. Keys are evicted at once if the
+// cache holds more than the new Max, and a new Policy starts out treating
+// existing keys as added in the order they were created. Keys already in
+// the cache keep their expiry. It returns an error wrapping
+// ErrInvalidConfig, changing nothing, if the settings are invalid.
+func (p *Cache) Reconfigure(s Settings) error {
+	p.Lock()
+	defer p.Unlock()
+
+	switch {
+	case s.Max < 0:
+		return configError("Max is negative")
+	case s.Max > 0 && p.HighWatermark == 0 && s.NEvictions <= 0:
+		return configError("NEvictions must be positive when Max is set")
+	case s.Policy < PolicySampled || s.Policy > PolicyRandom:
+		return configError("unknown Policy %d", s.Policy)
+	}
+
+	rebuild := s.Max != p.Max || s.Policy != p.Policy
+	p.Max, p.NEvictions, p.Duration, p.Policy = s.Max, s.NEvictions,
+		s.Duration, s.Policy
+
+	if rebuild {
+		p.rebuildPolicy()
+	}
+
+	if p.HighWatermark == 0 {
+		for p.Max > 0 && p.data.Len() > p.Max && p.evictKey() {
+		}
+	}
+
+	return nil
+}
+
+// rebuildPolicy replaces the policy tracker to match the settings. It must
+// be called with the lock held.
+func (p *Cache) rebuildPolicy() {
+	// EvictionPolicy already tracks the entries
+	if p.EvictionPolicy != nil {
+		return
+	}
+
+	p.policy = p.newPolicy()
+	if p.policy == nil {
+		return
+	}
+
+	entries := make([]*CacheValue, p.data.Len())
+	for i := range entries {
+		entries[i] = p.data.At(i).(*CacheValue)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt < entries[j].CreatedAt
+	})
+
+	for _, cv := range entries {
+		p.policy.OnAdd(cv)
+	}
+}
@@ -0,0 +1,51 @@
+package expiringcache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReconfigure(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 10, NEvictions: 1, EvictSoonest: true}
+	cache.Init()
+
+	for i := 0; i < 10; i++ {
+		cache.PutWithExpiry(Key(i), i, 60+i)
+	}
+
+	s := cache.Settings()
+	s.Max = 5
+	s.Duration = 120
+	s.Policy = PolicyFIFO
+	if err := cache.Reconfigure(s); err != nil {
+		t.Fatalf("Reconfigure failed: %v", err)
+	}
+
+	if cache.Count() != 5 {
+		t.Errorf("Cache kept %d keys after lowering Max to 5", cache.Count())
+	}
+
+	// FIFO evicts the oldest key left
+	oldest := ""
+	for i := 0; i < 10 && oldest == ""; i++ {
+		if cache.Exists(Key(i)) {
+			oldest = Key(i)
+		}
+	}
+	cache.Put("new", 1)
+	if cache.Exists(oldest) || cache.Count() != 5 {
+		t.Errorf("New policy didn't evict the oldest key %s", oldest)
+	}
+	if d, _ := cache.TTL("new"); d < 118*time.Second {
+		t.Errorf("New key has a TTL of %v, expected 2m", d)
+	}
+
+	s.NEvictions = 0
+	if err := cache.Reconfigure(s); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Reconfigure with invalid settings returned %v", err)
+	}
+	if cache.Settings().NEvictions != 1 {
+		t.Errorf("Invalid settings were applied")
+	}
+}
@@ -0,0 +1,65 @@
+package expiringcache
+
+import (
+	"context"
+)
+
+// maybeRefresh starts a background reload of cv if it is stale or due for
+// a refresh ahead of expiry. It must be called with the lock held.
+func (p *Cache) maybeRefresh(cv *CacheValue) {
+	if p.Loader == nil || cv.refreshing {
+		return
+	}
+
+	ts := p.now()
+	due := cv.stale(ts)
+	if !due && p.RefreshAhead > 0 && cv.ExpireAt != 0 {
+		refreshAt := float64(cv.ExpireAt) - float64(cv.ttl)*(1-p.RefreshAhead)
+		due = float64(ts) >= refreshAt
+	}
+
+	if !due {
+		return
+	}
+
+	cv.refreshing = true
+	key, version := cv.Key, cv.Version
+	p.background(func() { p.refresh(key, version) })
+}
+
+// refresh reloads key, which was at version when the refresh started
+func (p *Cache) refresh(key string, version uint64) {
+	value, err := p.load(context.Background(), key)
+	if err == nil {
+		value, err = limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	}
+	if err != nil {
+		p.logger().Error("expiringcache: refresh failed", "key", key,
+			"err", err)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// the key may have been removed while loading; don't bring it back
+	cv := p.find(key)
+	if cv == nil {
+		return
+	}
+
+	cv.refreshing = false
+
+	// a write while loading is newer than what was loaded
+	if err != nil || cv.Version != version {
+		return
+	}
+
+	duration := int(cv.duration)
+	if p.TTLFunc != nil {
+		duration = p.defaultDuration(key, value)
+	}
+
+	p.put(key, value, entryOptions{duration: duration,
+		priority: cv.Priority, soft: int(cv.softTTL), metadata: cv.Metadata,
+		deps: cv.deps})
+}
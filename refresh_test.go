@@ -0,0 +1,87 @@
+package expiringcache
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRefreshAhead(t *testing.T) {
+	cache := Cache{Duration: 2, RefreshAhead: 0.5,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			return "fresh", nil
+		}}
+	cache.Init()
+
+	cache.Put("a", "stale")
+
+	// too early for a refresh
+	cache.Get("a")
+	if cache.Get("a").(string) != "stale" {
+		t.Errorf("Key refreshed too early")
+	}
+
+	events, cancel := cache.Subscribe()
+	defer cancel()
+
+	time.Sleep(1100 * time.Millisecond)
+	cache.Get("a")
+
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatalf("Key not refreshed in the background")
+	}
+
+	if cache.Get("a").(string) != "fresh" {
+		t.Errorf("Refresh did not update the value")
+	}
+}
+
+func TestRefreshKeepsNewerWrite(t *testing.T) {
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			return "loaded", nil
+		}}
+	cache.Init()
+
+	cache.Put("a", "old")
+	cache.Lock()
+	version := cache.find("a").Version
+	cache.Unlock()
+
+	// written while the load was running
+	cache.Put("a", "new")
+	cache.refresh("a", version)
+
+	if v := cache.Get("a"); v != "new" {
+		t.Errorf("Refresh replaced a newer write with %v", v)
+	}
+}
+
+func TestRefreshKeepsDuration(t *testing.T) {
+	now := time.Now()
+	cache := Cache{Duration: 60, TTLJitter: 0.5,
+		RandSource: rand.NewSource(1),
+		Clock:      func() time.Time { return now },
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			return 1, nil
+		}}
+	cache.Init()
+
+	cache.Put("a", 1)
+	for i := 0; i < 20; i++ {
+		cache.Lock()
+		version := cache.find("a").Version
+		cache.Unlock()
+		cache.refresh("a", version)
+
+		// jitter applies to the duration the key was stored for, not to
+		// the jittered TTL of the last refresh
+		if ttl, _ := cache.TTL("a"); ttl < 30*time.Second ||
+			ttl > 90*time.Second {
+			t.Fatalf("TTL drifted to %v after %d refreshes", ttl, i+1)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package expiringcache
+
+// Rename moves the entry of oldKey to newKey in one step, keeping its
+// value, expiry, priority and metadata, and replacing any entry newKey
+// had. The expiry is kept exactly, without applying MinDuration and
+// MaxDuration again. It returns false if oldKey is missing or has expired, or if newKey
+// can't be stored, e.g. because it is invalid. Subscribers see EventPut
+// for newKey and EventDelete for oldKey, and keys depending on oldKey (see
+// PutWithDeps) are removed as usual.
+func (p *Cache) Rename(oldKey, newKey string) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	oldKey, newKey = p.canonicalKey(oldKey), p.canonicalKey(newKey)
+	cv := p.find(oldKey)
+	if cv == nil || cv.expired(p.now()) {
+		return false
+	}
+	if oldKey == newKey {
+		return true
+	}
+
+	// the stored value is moved as is, only decoding it to store it again
+	value := cv.Value
+	if p.Codec != nil {
+		value = p.clone(value)
+	}
+
+	_, _, err := p.put(newKey, value, entryOptions{expireAt: cv.ExpireAt,
+		priority: cv.Priority, metadata: cv.Metadata, moved: true})
+	if err != nil {
+		return false
+	}
+
+	moved := p.find(newKey)
+	if moved == nil {
+		// dropped by WritePolicy or the Admitter
+		return false
+	}
+	moved.StaleAt, moved.ttl, moved.softTTL = cv.StaleAt, cv.ttl, cv.softTTL
+	moved.duration = cv.duration
+
+	// the value isn't disposed of as it is still in the cache
+	if cv.ref != nil {
+		moved.ref, cv.ref = cv.ref, nil
+	}
+
+	// storing newKey may have evicted oldKey already
+	if p.find(oldKey) == cv {
+		p.remove(cv, EventDelete)
+	}
+	return true
+}
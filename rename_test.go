@@ -0,0 +1,64 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRename(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var disposed []interface{}
+	cache := Cache{Duration: 60, Clock: func() time.Time { return now },
+		Disposer: func(v interface{}) { disposed = append(disposed, v) }}
+	cache.Init()
+
+	cache.PutWithMetadata("draft", 1, map[string]string{"state": "draft"})
+	cache.PutWithExpiry("published", 2, 0)
+	now = now.Add(10 * time.Second)
+
+	if !cache.Rename("draft", "published") {
+		t.Fatalf("Rename failed")
+	}
+	if cache.Exists("draft") {
+		t.Errorf("Old key still present")
+	}
+	if v := cache.Get("published"); v != 1 {
+		t.Errorf("New key has %v", v)
+	}
+	if d, _ := cache.TTL("published"); d != 50*time.Second {
+		t.Errorf("TTL not kept: %v", d)
+	}
+	if m, _ := cache.Metadata("published"); m["state"] != "draft" {
+		t.Errorf("Metadata not kept: %v", m)
+	}
+	if len(disposed) != 1 || disposed[0] != 2 {
+		t.Errorf("Disposed of %v, expected only the replaced value", disposed)
+	}
+
+	if cache.Rename("missing", "x") {
+		t.Errorf("Renamed a missing key")
+	}
+	now = now.Add(time.Minute)
+	if cache.Rename("published", "x") {
+		t.Errorf("Renamed an expired key")
+	}
+}
+
+func TestRenameKeepsExpiry(t *testing.T) {
+	for _, policy := range []DurationPolicy{ClampDuration, RejectDuration} {
+		now := time.Unix(1000, 0)
+		cache := Cache{MinDuration: 30, DurationPolicy: policy,
+			Clock: func() time.Time { return now }}
+		cache.Init()
+		cache.PutWithExpiry("a", 1, 30)
+
+		// less than MinDuration left
+		now = now.Add(25 * time.Second)
+		if !cache.Rename("a", "b") {
+			t.Fatalf("Rename failed with policy %v", policy)
+		}
+		if d, _ := cache.TTL("b"); d != 5*time.Second {
+			t.Errorf("TTL changed to %v with policy %v", d, policy)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package expiringcache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// A replication stream is replicationMagic, the format version as 2
+// big-endian bytes and changes framed as snapshot records, each an op
+// followed by a snapshot record for replPut or the key for replDelete.
+const (
+	replicationMagic   = "EXPR"
+	replicationVersion = 1
+)
+
+const (
+	replPut byte = iota + 1
+	replDelete
+)
+
+// Replicate streams the changes made to the cache to w, where a follower
+// applies them with Follow, e.g. to keep a warm standby for failover. It
+// starts with a copy of all entries, then sends every change as it is
+// published (see Subscribe) until ctx is done or writing fails. Values are
+// encoded as by Save.
+//
+// Replication is asynchronous: changes are buffered up to EventBufferSize
+// and if the follower falls further behind Replicate returns
+// ErrReplicationLag. Changes to expiry alone, e.g. by TouchMany, and the
+// removals done by Close are not sent. Run one Replicate per follower.
+func (p *Cache) Replicate(ctx context.Context, w io.Writer) error {
+	n := p.EventBufferSize
+	if n == 0 {
+		n = defaultEventBufferSize
+	}
+
+	// subscribe before copying so no change is missed, changes made in
+	// between are sent twice which is harmless
+	p.Lock()
+	if p.closed {
+		p.Unlock()
+		return ErrClosed
+	}
+	s := p.subscribe(n, nil)
+	s.disconnect = true
+	records := p.snapshotRecords()
+	p.Unlock()
+	defer p.cancelFunc(s)()
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(replicationMagic)
+	binary.Write(bw, binary.BigEndian, uint16(replicationVersion))
+
+	for _, r := range records {
+		if err := p.writeReplPut(bw, r); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case e, ok := <-s.c:
+			if !ok {
+				return ErrReplicationLag
+			}
+
+			switch e.Type {
+			case EventPut, EventUpdate:
+				// the key may have changed since, which is sent later
+				p.Lock()
+				v := p.find(e.Key)
+				var r snapshotRecord
+				if v != nil {
+					r = p.snapshotRecord(v)
+				}
+				p.Unlock()
+
+				if v == nil {
+					continue
+				}
+				if err := p.writeReplPut(bw, r); err != nil {
+					return err
+				}
+
+			case EventDelete, EventExpire, EventEvict:
+				b := append([]byte{replDelete}, e.Key...)
+				writeSnapshotChunk(bw, b)
+			}
+
+			// batch writes while changes are queued
+			if len(s.c) == 0 {
+				if err := bw.Flush(); err != nil {
+					return err
+				}
+			}
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *Cache) writeReplPut(w *bufio.Writer, r snapshotRecord) error {
+	b, err := p.encodeRecord(r)
+	if err != nil {
+		return fmt.Errorf("expiringcache: replicating %q: %w", r.key, err)
+	}
+
+	writeSnapshotChunk(w, append([]byte{replPut}, b...))
+	return nil
+}
+
+// Follow applies the changes streamed by Replicate from r until it ends,
+// returning nil if it ends cleanly. The cache should not be written to
+// otherwise, so it stays a copy of the primary.
+func (p *Cache) Follow(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(replicationMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil ||
+		string(header[:len(replicationMagic)]) != replicationMagic {
+		return fmt.Errorf("%w: not a replication stream", ErrSnapshot)
+	}
+	version := binary.BigEndian.Uint16(header[len(replicationMagic):])
+	if version != replicationVersion {
+		return fmt.Errorf("%w: unsupported replication version %d",
+			ErrSnapshot, version)
+	}
+
+	for {
+		var size uint32
+		if err := binary.Read(br, binary.BigEndian, &size); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%w: truncated change", ErrSnapshot)
+		}
+
+		b, err := readSnapshotChunk(br, size)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSnapshot, err)
+		}
+		if len(b) == 0 {
+			return fmt.Errorf("%w: empty change", ErrSnapshot)
+		}
+
+		switch b[0] {
+		case replPut:
+			rec, err := p.decodeRecord(b[1:])
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrSnapshot, err)
+			}
+			if err := p.loadRecord(rec); err != nil {
+				return err
+			}
+
+		case replDelete:
+			p.Del(string(b[1:]))
+
+		default:
+			return fmt.Errorf("%w: unknown change %d", ErrSnapshot, b[0])
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package expiringcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// eventually fails the test if cond doesn't hold within a second
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReplicate(t *testing.T) {
+	primary := &Cache{Duration: 60}
+	primary.Init()
+	primary.Put("a", 1)
+
+	follower := &Cache{}
+	follower.Init()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pr, pw := io.Pipe()
+	replicated := make(chan error, 1)
+	go func() {
+		replicated <- primary.Replicate(ctx, pw)
+		pw.Close()
+	}()
+	followed := make(chan error, 1)
+	go func() {
+		followed <- follower.Follow(pr)
+	}()
+
+	eventually(t, "the initial copy", func() bool {
+		return follower.Get("a") == 1
+	})
+
+	primary.Put("b", 2)
+	primary.Put("a", 3)
+	primary.Del("b")
+	primary.PutWithExpiry("c", 4, 0)
+
+	eventually(t, "changes", func() bool {
+		return follower.Get("a") == 3 && !follower.Exists("b") &&
+			follower.Get("c") == 4
+	})
+	if d, _ := follower.TTL("a"); d < 58*time.Second {
+		t.Errorf("Replicated key has a TTL of %v", d)
+	}
+	if d, _ := follower.TTL("c"); d != NoExpiry {
+		t.Errorf("Replicated key without expiry has a TTL of %v", d)
+	}
+
+	cancel()
+	if err := <-replicated; err != context.Canceled {
+		t.Errorf("Replicate returned %v", err)
+	}
+	if err := <-followed; err != nil {
+		t.Errorf("Follow returned %v", err)
+	}
+}
+
+// blockingWriter blocks writes until unblock is closed
+type blockingWriter struct {
+	unblock chan struct{}
+	writes  int
+}
+
+func (w *blockingWriter) Write(b []byte) (int, error) {
+	if w.writes++; w.writes > 1 {
+		<-w.unblock
+	}
+	return len(b), nil
+}
+
+func TestReplicateLag(t *testing.T) {
+	primary := &Cache{Duration: 60, EventBufferSize: 1}
+	primary.Init()
+
+	w := &blockingWriter{unblock: make(chan struct{})}
+	replicated := make(chan error, 1)
+	go func() {
+		replicated <- primary.Replicate(context.Background(), w)
+	}()
+
+	// wait for the subscription, then queue more changes than fit
+	eventually(t, "the subscription", func() bool {
+		primary.Lock()
+		defer primary.Unlock()
+		return len(primary.subs) == 1
+	})
+	for i := 0; i < 10; i++ {
+		primary.Put(Key(i), i)
+	}
+	close(w.unblock)
+
+	if err := <-replicated; err != ErrReplicationLag {
+		t.Errorf("Replicate returned %v", err)
+	}
+}
+
+func FuzzFollow(f *testing.F) {
+	primary := &Cache{Duration: 60}
+	primary.Init()
+	primary.Put("a", 1)
+	primary.PutWithMetadata("b", "x", map[string]string{"owner": "me"})
+
+	// a cancelled Replicate stops after the initial copy
+	var buf bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	primary.Replicate(ctx, &buf)
+
+	bw := bufio.NewWriter(&buf)
+	writeSnapshotChunk(bw, append([]byte{replDelete}, "a"...))
+	bw.Flush()
+
+	check := &Cache{}
+	check.Init()
+	if err := check.Follow(bytes.NewReader(buf.Bytes())); err != nil ||
+		check.Exists("a") || check.Get("b") != "x" {
+		f.Fatalf("Follow of the seed returned %v", err)
+	}
+
+	f.Add(buf.Bytes())
+	f.Add(buf.Bytes()[:buf.Len()-3])
+	f.Add([]byte("junk"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		follower := &Cache{}
+		follower.Init()
+
+		if err := follower.Follow(bytes.NewReader(data)); err != nil &&
+			!errors.Is(err, ErrSnapshot) {
+			t.Errorf("Follow returned %v", err)
+		}
+	})
+}
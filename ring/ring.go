@@ -0,0 +1,153 @@
+// Package ring spreads keys over several caches or cache servers with
+// consistent hashing, so adding or removing one moves only the keys it
+// gains or loses:
+//
+//	r := ring.Ring{}
+//	r.Init()
+//	r.Add("cache-1:7000", "cache-2:7000", "cache-3:7000")
+//	client := clients[r.Get(key)]
+package ring
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const defaultReplicas = 128
+
+// Ring maps keys to nodes, identified by name, e.g. their address. It is
+// safe for concurrent use.
+type Ring struct {
+	// Number of points on the ring per node. More spread keys more evenly
+	// at the cost of memory. Defaults to 128.
+	Replicas int
+	// Hash places keys and nodes on the ring. Every client of the same
+	// nodes must use the same hash. Defaults to expiringcache.FNVHash.
+	Hash expiringcache.HashFunc
+
+	points []point
+	nodes  map[string]struct{}
+	sync.RWMutex
+}
+
+// point is a virtual node
+type point struct {
+	hash uint64
+	node string
+}
+
+func (p *Ring) Init() {
+	if p.Replicas <= 0 {
+		p.Replicas = defaultReplicas
+	}
+	if p.Hash == nil {
+		p.Hash = expiringcache.FNVHash
+	}
+	p.nodes = make(map[string]struct{})
+}
+
+// Add puts nodes on the ring. Nodes already on it are ignored.
+func (p *Ring) Add(nodes ...string) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, node := range nodes {
+		if _, ok := p.nodes[node]; ok {
+			continue
+		}
+
+		p.nodes[node] = struct{}{}
+		for i := 0; i < p.Replicas; i++ {
+			p.points = append(p.points,
+				point{hash: p.Hash(strconv.Itoa(i) + "-" + node), node: node})
+		}
+	}
+
+	sort.Slice(p.points, func(i, j int) bool {
+		if p.points[i].hash != p.points[j].hash {
+			return p.points[i].hash < p.points[j].hash
+		}
+		// break ties the same way in every client
+		return p.points[i].node < p.points[j].node
+	})
+}
+
+// Remove takes node off the ring, moving its keys to the other nodes
+func (p *Ring) Remove(node string) {
+	p.Lock()
+	defer p.Unlock()
+
+	if _, ok := p.nodes[node]; !ok {
+		return
+	}
+	delete(p.nodes, node)
+
+	points := p.points[:0]
+	for _, pt := range p.points {
+		if pt.node != node {
+			points = append(points, pt)
+		}
+	}
+	p.points = points
+}
+
+// Nodes returns the nodes on the ring, sorted
+func (p *Ring) Nodes() []string {
+	p.RLock()
+	defer p.RUnlock()
+
+	nodes := make([]string, 0, len(p.nodes))
+	for node := range p.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Get returns the node key belongs to, or "" if the ring is empty
+func (p *Ring) Get(key string) string {
+	nodes := p.GetN(key, 1)
+	if len(nodes) == 0 {
+		return ""
+	}
+	return nodes[0]
+}
+
+// GetN returns up to n distinct nodes for key, the first being the one
+// returned by Get, e.g. to store copies of a key on several nodes
+func (p *Ring) GetN(key string, n int) []string {
+	p.RLock()
+	defer p.RUnlock()
+
+	if n > len(p.nodes) {
+		n = len(p.nodes)
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	h := p.Hash(key)
+	i := sort.Search(len(p.points), func(i int) bool {
+		return p.points[i].hash >= h
+	})
+
+	nodes := make([]string, 0, n)
+	for j := 0; len(nodes) < n; j++ {
+		node := p.points[(i+j)%len(p.points)].node
+
+		found := false
+		for _, seen := range nodes {
+			if seen == node {
+				found = true
+				break
+			}
+		}
+		if !found {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,53 @@
+package ring
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r := Ring{}
+	r.Init()
+
+	if r.Get("a") != "" {
+		t.Errorf("Empty ring returned a node")
+	}
+
+	r.Add("n1", "n2", "n3")
+
+	counts := make(map[string]int)
+	before := make(map[string]string)
+	for i := 0; i < 10000; i++ {
+		key := fmt.Sprint("key", i)
+		node := r.Get(key)
+		counts[node]++
+		before[key] = node
+	}
+
+	for node, n := range counts {
+		if n < 2000 || n > 4700 {
+			t.Errorf("Node %s got %d of 10000 keys", node, n)
+		}
+	}
+
+	r.Add("n4")
+	for key, node := range before {
+		if now := r.Get(key); now != node && now != "n4" {
+			t.Errorf("Key %s moved from %s to %s, not the new node", key,
+				node, now)
+		}
+	}
+
+	r.Remove("n4")
+	for key, node := range before {
+		if r.Get(key) != node {
+			t.Errorf("Key %s not back on %s after removing a node", key, node)
+		}
+	}
+
+	nodes := r.GetN("key1", 5)
+	if len(nodes) != 3 || nodes[0] != r.Get("key1") || nodes[1] == nodes[0] ||
+		nodes[2] == nodes[1] || nodes[2] == nodes[0] {
+		t.Errorf("GetN returned %v", nodes)
+	}
+}
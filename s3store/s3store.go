@@ -0,0 +1,163 @@
+// Package s3store keeps expiringcache snapshots in S3 or S3-compatible
+// object storage, e.g. so ephemeral containers can warm their caches at
+// boot:
+//
+//	store := &s3store.Store{Endpoint: "https://s3.eu-west-1.amazonaws.com",
+//		Region: "eu-west-1", Bucket: "caches", Key: "sessions.snap",
+//		AccessKeyID: id, SecretAccessKey: secret}
+//	cache.LoadFrom(ctx, store)
+//	...
+//	cache.SaveTo(ctx, store)
+package s3store
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Store is an expiringcache.SnapshotStore keeping the snapshot as an
+// object, addressed path-style as Endpoint/Bucket/Key. Requests are signed
+// with AWS Signature Version 4.
+type Store struct {
+	Endpoint string // e.g. https://s3.us-east-1.amazonaws.com
+	Region   string
+	Bucket   string
+	Key      string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // for temporary credentials, may be empty
+
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Returns the current time for signing. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// Put uploads the snapshot read from r. It is read fully into memory first,
+// as the upload must be signed with its hash.
+func (p *Store) Put(ctx context.Context, r io.Reader) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.do(ctx, http.MethodPut, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads the snapshot
+func (p *Store) Get(ctx context.Context) (io.ReadCloser, error) {
+	resp, err := p.do(ctx, http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// do sends a signed request for the object, failing on any status but 200
+func (p *Store) do(ctx context.Context, method string,
+	body []byte) (*http.Response, error) {
+	u, err := url.Parse(strings.TrimSuffix(p.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	u.Path += "/" + p.Bucket + "/" + p.Key
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(),
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	p.sign(req, body)
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("s3store: %s %s: %s: %s", method, u.Path,
+			resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return resp, nil
+}
+
+// sign adds the headers of AWS Signature Version 4 to req
+func (p *Store) sign(req *http.Request, body []byte) {
+	now := time.Now
+	if p.Clock != nil {
+		now = p.Clock
+	}
+	ts := now().UTC()
+	amzDate := ts.Format("20060102T150405Z")
+	date := ts.Format("20060102")
+
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if p.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.SessionToken)
+	}
+
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := []string{req.URL.Host, payloadHash, amzDate}
+	if p.SessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+		values = append(values, p.SessionToken)
+	}
+
+	var canonicalHeaders strings.Builder
+	for i, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + values[i] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method,
+		req.URL.EscapedPath(), req.URL.RawQuery,
+		canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + p.Region + "/s3/aws4_request"
+	crSum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" +
+		hex.EncodeToString(crSum[:])
+
+	key := hmacSHA256([]byte("AWS4"+p.SecretAccessKey), date)
+	key = hmacSHA256(key, p.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+
+		p.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+
+		", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
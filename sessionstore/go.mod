@@ -0,0 +1,11 @@
+module github.com/deep-compute/expiringcache/sessionstore
+
+go 1.21
+
+require (
+	github.com/deep-compute/expiringcache v0.0.0
+	github.com/gorilla/securecookie v1.1.2
+	github.com/gorilla/sessions v1.2.2
+)
+
+replace github.com/deep-compute/expiringcache => ../
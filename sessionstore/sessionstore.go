@@ -0,0 +1,150 @@
+// Package sessionstore implements a gorilla/sessions Store keeping session
+// data in an expiringcache.Cache. Only the session ID is stored in the
+// cookie. It is a module of its own so that expiringcache itself doesn't
+// depend on gorilla.
+package sessionstore
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/gob"
+	"github.com/deep-compute/expiringcache"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Serializer encodes session values for storage in the cache
+type Serializer interface {
+	Serialize(s *sessions.Session) ([]byte, error)
+	Deserialize(b []byte, s *sessions.Session) error
+}
+
+// GobSerializer encodes session values with encoding/gob. Custom types
+// stored in sessions must be registered with gob.Register.
+type GobSerializer struct{}
+
+func (GobSerializer) Serialize(s *sessions.Session) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.Values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobSerializer) Deserialize(b []byte, s *sessions.Session) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(&s.Values)
+}
+
+// Store is a sessions.Store backed by a Cache. Sessions expire after
+// Options.MaxAge seconds without being used: each load extends a session's
+// lifetime (sliding expiration).
+type Store struct {
+	Cache      *expiringcache.Cache
+	Codecs     []securecookie.Codec
+	Options    *sessions.Options // default options of new sessions
+	Serializer Serializer
+	KeyPrefix  string // prefix of the cache keys of sessions
+}
+
+// NewStore returns a Store keeping sessions in cache for 30 days. keyPairs
+// are used to authenticate (and optionally encrypt) the session cookie as
+// described in securecookie.CodecsFromPairs.
+func NewStore(cache *expiringcache.Cache, keyPairs ...[]byte) *Store {
+	return &Store{
+		Cache:      cache,
+		Codecs:     securecookie.CodecsFromPairs(keyPairs...),
+		Options:    &sessions.Options{Path: "/", MaxAge: 86400 * 30},
+		Serializer: GobSerializer{},
+		KeyPrefix:  "session_",
+	}
+}
+
+// Get returns the session called name, as registered for r
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns the session called name from the cache, or a new session if
+// r has no valid session cookie or the session has expired.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
+	if err != nil {
+		return session, err
+	}
+
+	// Fetch rather than Get so sessions past their MaxAge are rejected even
+	// if they haven't been swept yet
+	key := s.KeyPrefix + session.ID
+	v, err := s.Cache.Fetch(key)
+	b, ok := v.([]byte)
+	if err != nil || !ok {
+		return session, nil
+	}
+
+	if err := s.Serializer.Deserialize(b, session); err != nil {
+		return session, err
+	}
+
+	// slide the expiry forward, leaving the value alone so a concurrent
+	// Save isn't overwritten
+	_, err = s.Cache.ExpireMany([]string{key},
+		time.Duration(session.Options.MaxAge)*time.Second)
+	if err != nil {
+		return session, err
+	}
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores session in the cache and sets its cookie. A session with a
+// MaxAge of 0 or less is deleted instead.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter,
+	session *sessions.Session) error {
+	if session.Options.MaxAge <= 0 {
+		if session.ID != "" {
+			s.Cache.Del(s.KeyPrefix + session.ID)
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "",
+			session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = strings.TrimRight(base32.StdEncoding.EncodeToString(
+			securecookie.GenerateRandomKey(32)), "=")
+	}
+
+	b, err := s.Serializer.Serialize(session)
+	if err != nil {
+		return err
+	}
+
+	err = s.Cache.SetWithExpiry(s.KeyPrefix+session.ID, b,
+		session.Options.MaxAge)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
+		s.Codecs...)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded,
+		session.Options))
+	return nil
+}
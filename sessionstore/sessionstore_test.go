@@ -0,0 +1,89 @@
+package sessionstore
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"github.com/gorilla/sessions"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	cache := expiringcache.Cache{Duration: 60}
+	cache.Init()
+	store := NewStore(&cache, []byte("secret-key"))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	session, err := store.Get(r, "s")
+	if err != nil || !session.IsNew {
+		t.Fatalf("Expected a new session, err %v", err)
+	}
+
+	session.Values["user"] = "alice"
+	w := httptest.NewRecorder()
+	if err := session.Save(r, w); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Session cookie not set")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookies[0])
+	session, err = store.Get(r, "s")
+	if err != nil || session.IsNew || session.Values["user"] != "alice" {
+		t.Errorf("Session not loaded from cache, err %v", err)
+	}
+
+	session.Options.MaxAge = -1
+	session.Save(r, httptest.NewRecorder())
+	if cache.Count() != 0 {
+		t.Errorf("Session not deleted from cache")
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := expiringcache.Cache{Clock: func() time.Time { return now }}
+	cache.Init()
+	store := NewStore(&cache, []byte("secret-key"))
+	store.Options.MaxAge = 10
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	session, _ := store.New(r, "s")
+	session.Values["user"] = "alice"
+	w := httptest.NewRecorder()
+	if err := store.Save(r, w, session); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	cookie := w.Result().Cookies()[0]
+
+	load := func() *sessions.Session {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookie)
+		session, err := store.New(r, "s")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		return session
+	}
+
+	// each load slides the expiry forward
+	now = now.Add(8 * time.Second)
+	if load().IsNew {
+		t.Fatalf("Session expired before its MaxAge")
+	}
+	now = now.Add(8 * time.Second)
+	if load().IsNew {
+		t.Fatalf("Loading the session didn't extend its lifetime")
+	}
+
+	// the cache is never swept, the expired session must still be rejected
+	now = now.Add(11 * time.Second)
+	if !load().IsNew || !load().IsNew {
+		t.Errorf("Expired session loaded")
+	}
+}
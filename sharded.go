@@ -0,0 +1,200 @@
+package expiringcache
+
+import (
+	"hash/fnv"
+	"hash/maphash"
+	"time"
+)
+
+const defaultShards = 16
+
+// HashFunc hashes keys to pick their shard in a ShardedCache
+type HashFunc func(key string) uint64
+
+// FNVHash hashes key with 64-bit FNV-1a. It is the same in every process,
+// so the same keys always land in the same shards.
+func FNVHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// MaphashHash returns a HashFunc using hash/maphash with a random seed,
+// which is faster than FNVHash and makes it hard to craft keys that all
+// land in one shard.
+func MaphashHash() HashFunc {
+	seed := maphash.MakeSeed()
+	return func(key string) uint64 {
+		return maphash.String(seed, key)
+	}
+}
+
+// ShardedCache spreads keys over several Caches, each with its own lock, so
+// that concurrent callers contend less than on a single Cache.
+type ShardedCache struct {
+	// Number of shards, rounded up to a power of two so a shard can be
+	// picked by masking the hash. Defaults to 16.
+	Shards int
+	// Hash picks the shard of a key. Defaults to FNVHash.
+	Hash HashFunc
+	// NewShard returns the configuration of each shard, on which Init is
+	// then called. Limits such as Max apply per shard. Defaults to a Cache
+	// with no options set.
+	NewShard func() *Cache
+
+	shards []*Cache
+	mask   uint64
+}
+
+func (p *ShardedCache) Init() {
+	n := p.Shards
+	if n <= 0 {
+		n = defaultShards
+	}
+
+	// round up to a power of two
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+
+	if p.Hash == nil {
+		p.Hash = FNVHash
+	}
+
+	p.shards = make([]*Cache, size)
+	for i := range p.shards {
+		c := &Cache{}
+		if p.NewShard != nil {
+			c = p.NewShard()
+		}
+		c.Init()
+		p.shards[i] = c
+	}
+	p.mask = uint64(size - 1)
+}
+
+// Shard returns the Cache holding key, to use any operation ShardedCache
+// doesn't provide itself
+func (p *ShardedCache) Shard(key string) *Cache {
+	return p.shards[p.Hash(key)&p.mask]
+}
+
+func (p *ShardedCache) Get(key string) interface{} {
+	return p.Shard(key).Get(key)
+}
+
+func (p *ShardedCache) Put(key string, value interface{}) {
+	p.Shard(key).Put(key, value)
+}
+
+func (p *ShardedCache) Set(key string, value interface{}) error {
+	return p.Shard(key).Set(key, value)
+}
+
+func (p *ShardedCache) PutWithExpiry(key string, value interface{},
+	duration int) {
+	p.Shard(key).PutWithExpiry(key, value, duration)
+}
+
+func (p *ShardedCache) SetWithExpiry(key string, value interface{},
+	duration int) error {
+	return p.Shard(key).SetWithExpiry(key, value, duration)
+}
+
+func (p *ShardedCache) Del(key string) {
+	p.Shard(key).Del(key)
+}
+
+func (p *ShardedCache) DelValue(key string) (interface{}, bool) {
+	return p.Shard(key).DelValue(key)
+}
+
+func (p *ShardedCache) Exists(key string) bool {
+	return p.Shard(key).Exists(key)
+}
+
+// TTL is like Cache.TTL
+func (p *ShardedCache) TTL(key string) (time.Duration, bool) {
+	return p.Shard(key).TTL(key)
+}
+
+// EvictExpired is like Cache.EvictExpired over all shards in turn, with
+// the limits applying to the total
+func (p *ShardedCache) EvictExpired(maxEntries int,
+	maxDuration time.Duration) int {
+	start := time.Now()
+	removed := 0
+	for _, c := range p.shards {
+		var left int
+		var d time.Duration
+		if maxEntries > 0 {
+			if left = maxEntries - removed; left <= 0 {
+				break
+			}
+		}
+		if maxDuration > 0 {
+			if d = maxDuration - time.Since(start); d <= 0 {
+				break
+			}
+		}
+
+		removed += c.EvictExpired(left, d)
+	}
+	return removed
+}
+
+// IterSnapshot is like Cache.IterSnapshot over all shards. The shards are
+// locked together while their entries are copied, so the view is
+// consistent across shards.
+func (p *ShardedCache) IterSnapshot() <-chan *CacheValue {
+	for _, c := range p.shards {
+		c.Lock()
+	}
+	var entries [][]CacheValue
+	for _, c := range p.shards {
+		entries = append(entries, c.entries())
+	}
+	for _, c := range p.shards {
+		c.Unlock()
+	}
+
+	wc := make(chan *CacheValue)
+	go func() {
+		for i, c := range p.shards {
+			for cv := range c.yieldEntries(entries[i]) {
+				wc <- cv
+			}
+		}
+
+		close(wc)
+	}()
+
+	return wc
+}
+
+// Count returns the number of keys in all shards
+func (p *ShardedCache) Count() int {
+	count := 0
+	for _, c := range p.shards {
+		count += c.Count()
+	}
+	return count
+}
+
+// ShardStat describes one shard of a ShardedCache
+type ShardStat struct {
+	Keys       int    // number of keys in the shard
+	Contention uint64 // see Cache.Contention
+}
+
+// ShardStats returns the statistics of every shard, to detect keys that
+// are not spread evenly, e.g. because of the hash or key patterns, and
+// shards that are contended enough to call for more of them.
+func (p *ShardedCache) ShardStats() []ShardStat {
+	stats := make([]ShardStat, len(p.shards))
+	for i, c := range p.shards {
+		stats[i] = ShardStat{Keys: c.Count(), Contention: c.Contention()}
+	}
+	return stats
+}
@@ -0,0 +1,87 @@
+package expiringcache
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func TestShardedCache(t *testing.T) {
+	for _, hash := range []HashFunc{FNVHash, MaphashHash()} {
+		cache := ShardedCache{Shards: 5, Hash: hash,
+			NewShard: func() *Cache { return &Cache{Duration: 60} }}
+		cache.Init()
+
+		if len(cache.shards) != 8 {
+			t.Errorf("%d shards, expected 5 rounded up to 8",
+				len(cache.shards))
+		}
+
+		for i := 0; i < 100; i++ {
+			cache.Put(strconv.Itoa(i), i)
+		}
+
+		if cache.Count() != 100 || cache.Get("42") != 42 {
+			t.Errorf("Keys lost across shards")
+		}
+
+		if _, ok := cache.DelValue("42"); !ok || cache.Exists("42") {
+			t.Errorf("Del did not remove key from its shard")
+		}
+
+		used, keys := 0, 0
+		for _, s := range cache.ShardStats() {
+			if s.Keys > 0 {
+				used++
+			}
+			keys += s.Keys
+		}
+		if used < 2 || keys != 99 {
+			t.Errorf("Keys not spread over shards")
+		}
+	}
+}
+
+func TestContention(t *testing.T) {
+	cache := Cache{}
+	cache.Init()
+
+	cache.Lock()
+	done := make(chan struct{})
+	go func() {
+		cache.Put("a", 1)
+		close(done)
+	}()
+
+	for cache.Contention() == 0 {
+		runtime.Gosched()
+	}
+	cache.Unlock()
+	<-done
+
+	if cache.Contention() != 1 {
+		t.Errorf("Contention is %d, expected 1", cache.Contention())
+	}
+}
+
+func TestShardedIterSnapshot(t *testing.T) {
+	cache := ShardedCache{Shards: 4}
+	cache.Init()
+
+	for i := 0; i < 100; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+
+	entries := cache.IterSnapshot()
+	for i := 0; i < 100; i++ {
+		cache.Del(strconv.Itoa(i))
+	}
+
+	n := 0
+	for range entries {
+		n++
+	}
+	if n != 100 {
+		t.Errorf("Snapshot yielded %d entries, expected 100", n)
+	}
+}
@@ -0,0 +1,124 @@
+// Package simulate replays a trace of cache accesses against an
+// expiringcache.Cache so that configurations (Max, eviction settings,
+// durations) can be compared by hit ratio offline, e.g. for capacity
+// planning.
+package simulate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/deep-compute/expiringcache"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Op is the kind of an access
+type Op int
+
+const (
+	OpGet Op = iota // a read, which stores the key on a miss
+	OpPut           // a write
+	OpDel           // a delete
+)
+
+// Access is one operation of a trace
+type Access struct {
+	Time time.Time
+	Op   Op
+	Key  string
+}
+
+// Result summarizes a replay
+type Result struct {
+	Gets    int // number of OpGet accesses
+	Hits    int // gets that found the key before it expired
+	Expired int // gets that found the key after it expired, counted as misses
+}
+
+// HitRatio returns the fraction of gets that were hits
+func (r Result) HitRatio() float64 {
+	if r.Gets == 0 {
+		return 0
+	}
+	return float64(r.Hits) / float64(r.Gets)
+}
+
+// Run replays trace, which must be in time order, against cache and reports
+// how it performed. cache must be configured but not yet initialized: Run
+// sets its Clock to follow the times of the trace, disables the periodic
+// eviction, which works in real time, and calls Init. Gets that miss store
+// the key as a cache-aside caller would, with the cache's default duration.
+func Run(cache *expiringcache.Cache, trace []Access) Result {
+	var ts time.Time
+	cache.Clock = func() time.Time { return ts }
+	cache.PeriodicEvictionInterval = 0
+	cache.Init()
+
+	var r Result
+	for _, a := range trace {
+		ts = a.Time
+
+		switch a.Op {
+		case OpGet:
+			r.Gets++
+			if cache.Get(a.Key) != nil {
+				at, _ := cache.ExpiresAt(a.Key)
+				if at.IsZero() || at.Unix() > ts.Unix() {
+					r.Hits++
+					continue
+				}
+				r.Expired++
+			}
+			cache.Put(a.Key, true)
+
+		case OpPut:
+			cache.Put(a.Key, true)
+
+		case OpDel:
+			cache.Del(a.Key)
+		}
+	}
+
+	return r
+}
+
+// ReadTrace reads a trace from CSV records of the form
+// "unix_seconds,op,key", where op is get, put or del and unix_seconds may
+// have a fractional part.
+func ReadTrace(r io.Reader) ([]Access, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 3
+
+	var trace []Access
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return trace, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		secs, err := strconv.ParseFloat(record[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("simulate: bad time %q", record[0])
+		}
+
+		var op Op
+		switch record[1] {
+		case "get":
+			op = OpGet
+		case "put":
+			op = OpPut
+		case "del":
+			op = OpDel
+		default:
+			return nil, fmt.Errorf("simulate: unknown op %q", record[1])
+		}
+
+		trace = append(trace, Access{
+			Time: time.Unix(0, int64(secs*float64(time.Second))),
+			Op:   op, Key: record[2]})
+	}
+}
@@ -0,0 +1,50 @@
+package simulate
+
+import (
+	"github.com/deep-compute/expiringcache"
+	"strings"
+	"testing"
+)
+
+const trace = `0,get,a
+1,get,b
+2,get,a
+3,get,c
+4,get,a
+20,get,a
+21,del,a
+22,get,a
+`
+
+func TestRun(t *testing.T) {
+	accesses, err := ReadTrace(strings.NewReader(trace))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "a" stays cached until it expires at 10
+	r := Run(&expiringcache.Cache{Duration: 10}, accesses)
+	if r.Gets != 7 || r.Hits != 2 || r.Expired != 1 {
+		t.Errorf("Unexpected result %+v", r)
+	}
+
+	big := Run(&expiringcache.Cache{Duration: 60}, accesses)
+	if big.Hits != 3 {
+		t.Errorf("Unexpected result %+v", big)
+	}
+
+	// room for only two keys, so "c" evicts "a"
+	small := Run(&expiringcache.Cache{Duration: 60, Max: 2, NEvictions: 1,
+		EvictSoonest: true}, accesses)
+	if small.Hits != 2 || small.HitRatio() >= big.HitRatio() {
+		t.Errorf("Smaller cache did not lower hits: %+v", small)
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	for _, s := range []string{"x,get,a\n", "1,list,a\n", "1,get\n"} {
+		if _, err := ReadTrace(strings.NewReader(s)); err == nil {
+			t.Errorf("Invalid trace %q accepted", s)
+		}
+	}
+}
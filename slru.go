@@ -0,0 +1,77 @@
+package expiringcache
+
+import (
+	"container/list"
+)
+
+const defaultProtectedRatio = 0.8
+
+// slru implements the Segmented LRU policy. New keys enter probation and
+// move to the protected segment when used again, so keys used only once
+// are evicted before established ones. Both segments are ordered from the
+// most to the least recently used.
+type slru struct {
+	probation, protected *list.List
+	elems                map[string]*list.Element
+	lists                map[string]*list.List
+	capacity             int
+	ratio                float64 // share of the cache that is protected
+}
+
+func newSLRU(capacity int, ratio float64) *slru {
+	if ratio <= 0 {
+		ratio = defaultProtectedRatio
+	}
+	return &slru{probation: list.New(), protected: list.New(),
+		elems: make(map[string]*list.Element),
+		lists: make(map[string]*list.List), capacity: capacity,
+		ratio: ratio}
+}
+
+func (p *slru) OnAdd(cv *CacheValue) {
+	p.push(p.probation, cv)
+}
+
+func (p *slru) OnAccess(cv *CacheValue) {
+	switch p.lists[cv.Key] {
+	case p.protected:
+		p.protected.MoveToFront(p.elems[cv.Key])
+		return
+	case nil:
+		return
+	}
+
+	p.probation.Remove(p.elems[cv.Key])
+	p.push(p.protected, cv)
+
+	// demote the least recently used protected keys back to probation
+	size := max(p.capacity, p.probation.Len()+p.protected.Len())
+	for p.protected.Len() > 1 &&
+		float64(p.protected.Len()) > p.ratio*float64(size) {
+		p.push(p.probation,
+			p.protected.Remove(p.protected.Back()).(*CacheValue))
+	}
+}
+
+func (p *slru) OnRemove(cv *CacheValue, typ EventType) {
+	if l, ok := p.lists[cv.Key]; ok {
+		l.Remove(p.elems[cv.Key])
+		delete(p.elems, cv.Key)
+		delete(p.lists, cv.Key)
+	}
+}
+
+func (p *slru) Victim() *CacheValue {
+	if e := p.probation.Back(); e != nil {
+		return e.Value.(*CacheValue)
+	}
+	if e := p.protected.Back(); e != nil {
+		return e.Value.(*CacheValue)
+	}
+	return nil
+}
+
+func (p *slru) push(l *list.List, cv *CacheValue) {
+	p.elems[cv.Key] = l.PushFront(cv)
+	p.lists[cv.Key] = l
+}
@@ -0,0 +1,31 @@
+package expiringcache
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSLRU(t *testing.T) {
+	cache := Cache{Duration: 60, Max: 4, NEvictions: 1, Policy: PolicySLRU,
+		SLRUProtectedRatio: 0.5}
+	cache.Init()
+
+	for _, k := range []string{"a", "b", "c"} {
+		cache.Put(k, 1)
+		cache.Get(k)
+	}
+
+	// only two keys fit in the protected segment, so "a" is demoted
+	s := cache.policy.(*slru)
+	if s.protected.Len() != 2 || s.lists["a"] != s.probation {
+		t.Errorf("Protected segment not limited to its ratio")
+	}
+
+	for i := 0; i < 10; i++ {
+		cache.Put(strconv.Itoa(i), i)
+	}
+
+	if !cache.Exists("b") || !cache.Exists("c") || cache.Exists("a") {
+		t.Errorf("Scan evicted protected keys")
+	}
+}
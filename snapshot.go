@@ -0,0 +1,379 @@
+package expiringcache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// A snapshot is snapshotMagic, the format version as 2 big-endian bytes and
+// the entries, each as a 4 byte big-endian length, the record and the
+// CRC-32 of the record. A zero length followed by the CRC-32 of the number
+// of records ends the snapshot, so a truncated snapshot is detected.
+//
+// A record is the key, ExpireAt, Priority, the metadata and the value,
+// encoded with Codec or else GobCodec. Strings and byte slices are
+// prefixed with their length as a uvarint and numbers are varints.
+const (
+	snapshotMagic   = "EXPC"
+	snapshotVersion = 1
+)
+
+// largest record Load accepts, to fail fast on a corrupt length
+const maxSnapshotRecord = 1 << 30
+
+// snapshotRecord is an entry copied for writing without the lock
+type snapshotRecord struct {
+	key      string
+	expireAt int64
+	priority int
+	metadata map[string]string
+	value    interface{}
+}
+
+// Save writes a snapshot of the entries that have not expired to w, to be
+// restored with Load, e.g. by the next instance of a service. Values are
+// encoded with Codec if set and GobCodec otherwise, so their types must be
+// registered with gob.Register if they are stored in an interface{}.
+func (p *Cache) Save(w io.Writer) error {
+	p.Lock()
+	records := p.snapshotRecords()
+	p.Unlock()
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString(snapshotMagic)
+	binary.Write(bw, binary.BigEndian, uint16(snapshotVersion))
+
+	for _, r := range records {
+		b, err := p.encodeRecord(r)
+		if err != nil {
+			return fmt.Errorf("expiringcache: saving %q: %w", r.key, err)
+		}
+		writeSnapshotChunk(bw, b)
+	}
+
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], uint64(len(records)))
+	binary.Write(bw, binary.BigEndian, uint32(0))
+	bw.Write(count[:])
+	binary.Write(bw, binary.BigEndian, crc32.ChecksumIEEE(count[:]))
+
+	return bw.Flush()
+}
+
+// snapshotRecords copies the entries that have not expired. It must be
+// called with the lock held.
+func (p *Cache) snapshotRecords() []snapshotRecord {
+	var records []snapshotRecord
+
+	ts := p.now()
+	for i := 0; i < p.data.Len(); i++ {
+		cv := p.data.At(i).(*CacheValue)
+		if !cv.expired(ts) {
+			records = append(records, p.snapshotRecord(cv))
+		}
+	}
+
+	return records
+}
+
+// snapshotRecord copies cv. It must be called with the lock held.
+func (p *Cache) snapshotRecord(cv *CacheValue) snapshotRecord {
+	// encoded values are never modified, so they needn't be copied
+	value := cv.Value
+	if p.Codec == nil {
+		value = p.clone(value)
+	}
+
+	return snapshotRecord{key: cv.Key, expireAt: cv.ExpireAt,
+		priority: cv.Priority, metadata: copyMetadata(cv.Metadata),
+		value: value}
+}
+
+// writeSnapshotChunk writes b with its length and CRC. Errors are left to
+// the Flush of w.
+func writeSnapshotChunk(w *bufio.Writer, b []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(b)))
+	w.Write(b)
+	binary.Write(w, binary.BigEndian, crc32.ChecksumIEEE(b))
+}
+
+func (p *Cache) encodeRecord(r snapshotRecord) ([]byte, error) {
+	var value []byte
+	var err error
+	if p.Codec != nil {
+		value = r.value.([]byte)
+	} else if value, err = (GobCodec{}).Encode(r.value); err != nil {
+		return nil, err
+	}
+
+	b := binary.AppendUvarint(nil, uint64(len(r.key)))
+	b = append(b, r.key...)
+	b = binary.AppendVarint(b, r.expireAt)
+	b = binary.AppendVarint(b, int64(r.priority))
+	b = binary.AppendUvarint(b, uint64(len(r.metadata)))
+	for k, v := range r.metadata {
+		b = binary.AppendUvarint(b, uint64(len(k)))
+		b = append(b, k...)
+		b = binary.AppendUvarint(b, uint64(len(v)))
+		b = append(b, v...)
+	}
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...), nil
+}
+
+// Load adds the entries of a snapshot written by Save to the cache,
+// skipping those that have expired since, and returns the number of
+// entries read. If the snapshot is truncated or corrupt, the entries
+// before the damage are still added and an error wrapping ErrSnapshot is
+// returned.
+func (p *Cache) Load(r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(snapshotMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil ||
+		string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return 0, fmt.Errorf("%w: not a snapshot", ErrSnapshot)
+	}
+	version := binary.BigEndian.Uint16(header[len(snapshotMagic):])
+	if version != snapshotVersion {
+		return 0, fmt.Errorf("%w: unsupported version %d", ErrSnapshot,
+			version)
+	}
+
+	n := 0
+	for {
+		var size uint32
+		if err := binary.Read(br, binary.BigEndian, &size); err != nil {
+			return n, fmt.Errorf("%w: truncated after %d records",
+				ErrSnapshot, n)
+		}
+
+		// the end of the snapshot holds the number of records
+		if size == 0 {
+			b, err := readSnapshotChunk(br, 8)
+			if err != nil {
+				return n, fmt.Errorf("%w: end: %v", ErrSnapshot, err)
+			}
+			if binary.BigEndian.Uint64(b) != uint64(n) {
+				return n, fmt.Errorf("%w: expected %d records, read %d",
+					ErrSnapshot, binary.BigEndian.Uint64(b), n)
+			}
+			return n, nil
+		}
+
+		b, err := readSnapshotChunk(br, size)
+		if err != nil {
+			return n, fmt.Errorf("%w: record %d: %v", ErrSnapshot, n, err)
+		}
+
+		rec, err := p.decodeRecord(b)
+		if err != nil {
+			return n, fmt.Errorf("%w: record %d: %v", ErrSnapshot, n, err)
+		}
+
+		if err := p.loadRecord(rec); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// readSnapshotChunk reads size bytes and their CRC, checking it. The
+// buffer grows as the bytes arrive rather than being allocated up front,
+// so a corrupt length can't allocate much more than the input holds.
+func readSnapshotChunk(r io.Reader, size uint32) ([]byte, error) {
+	if size > maxSnapshotRecord {
+		return nil, errors.New("bad length")
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, int64(size)+4); err != nil {
+		return nil, errors.New("truncated")
+	}
+	b := buf.Bytes()
+
+	if binary.BigEndian.Uint32(b[size:]) != crc32.ChecksumIEEE(b[:size]) {
+		return nil, errors.New("checksum mismatch")
+	}
+	return b[:size], nil
+}
+
+// decodeRecord parses a record written by encodeRecord
+func (p *Cache) decodeRecord(b []byte) (snapshotRecord, error) {
+	d := recordDecoder{b: b}
+
+	var r snapshotRecord
+	r.key = string(d.bytes())
+	r.expireAt = d.varint()
+	r.priority = int(d.varint())
+	if n := d.uvarint(); n > 0 && d.err == nil {
+		r.metadata = make(map[string]string)
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			k := string(d.bytes())
+			r.metadata[k] = string(d.bytes())
+		}
+	}
+	value := d.bytes()
+	if d.err != nil {
+		return r, d.err
+	}
+
+	var err error
+	if p.Codec != nil {
+		r.value, err = p.Codec.Decode(value)
+	} else {
+		r.value, err = GobCodec{}.Decode(value)
+	}
+	return r, err
+}
+
+// loadRecord adds a record to the cache unless it has expired
+func (p *Cache) loadRecord(r snapshotRecord) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if r.expireAt != 0 && r.expireAt <= p.now() {
+		return nil
+	}
+
+	_, _, err := p.put(r.key, r.value, entryOptions{expireAt: r.expireAt,
+		priority: r.priority, metadata: r.metadata})
+	return err
+}
+
+// recordDecoder reads the fields of a record, remembering the first error
+type recordDecoder struct {
+	b   []byte
+	err error
+}
+
+var errShortRecord = errors.New("record too short")
+
+func (p *recordDecoder) uvarint() uint64 {
+	if p.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(p.b)
+	if n <= 0 {
+		p.err = errShortRecord
+		return 0
+	}
+	p.b = p.b[n:]
+	return v
+}
+
+func (p *recordDecoder) varint() int64 {
+	if p.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(p.b)
+	if n <= 0 {
+		p.err = errShortRecord
+		return 0
+	}
+	p.b = p.b[n:]
+	return v
+}
+
+func (p *recordDecoder) bytes() []byte {
+	n := p.uvarint()
+	if p.err != nil {
+		return nil
+	}
+
+	if n > uint64(len(p.b)) {
+		p.err = errShortRecord
+		return nil
+	}
+	b := p.b[:n]
+	p.b = p.b[n:]
+	return b
+}
+
+// SnapshotStore keeps a snapshot, e.g. in a file or object storage (see
+// the s3store package), for SaveTo and LoadFrom
+type SnapshotStore interface {
+	// Put replaces the snapshot with what is read from r. The previous
+	// snapshot should be kept if reading fails.
+	Put(ctx context.Context, r io.Reader) error
+	// Get returns a reader of the snapshot
+	Get(ctx context.Context) (io.ReadCloser, error)
+}
+
+// SaveTo writes a snapshot to store as with Save
+func (p *Cache) SaveTo(ctx context.Context, store SnapshotStore) error {
+	pr, pw := io.Pipe()
+	p.background(func() {
+		pw.CloseWithError(p.Save(pw))
+	})
+
+	err := store.Put(ctx, pr)
+	pr.CloseWithError(errors.New("expiringcache: snapshot not stored"))
+	return err
+}
+
+// LoadFrom adds the entries of the snapshot in store as with Load
+func (p *Cache) LoadFrom(ctx context.Context, store SnapshotStore) (int,
+	error) {
+	r, err := store.Get(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	return p.Load(r)
+}
+
+// FileStore keeps a snapshot in the file at Path. The file is replaced
+// atomically, so a crash while saving leaves the previous snapshot.
+type FileStore struct {
+	Path string
+}
+
+func (p FileStore) Put(ctx context.Context, r io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(p.Path),
+		filepath.Base(p.Path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), p.Path)
+}
+
+func (p FileStore) Get(ctx context.Context) (io.ReadCloser, error) {
+	return os.Open(p.Path)
+}
+
+// SaveToFile writes a snapshot to the file at path as with Save, using a
+// FileStore
+func (p *Cache) SaveToFile(path string) error {
+	return p.SaveTo(context.Background(), FileStore{Path: path})
+}
+
+// LoadFromFile adds the entries of the snapshot at path as with Load
+func (p *Cache) LoadFromFile(path string) (int, error) {
+	return p.LoadFrom(context.Background(), FileStore{Path: path})
+}
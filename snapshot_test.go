@@ -0,0 +1,137 @@
+package expiringcache
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.PutWithMetadata("b", "x", map[string]string{"owner": "me"})
+	cache.PutWithExpiry("c", []byte("y"), 0)
+	cache.PutWithDeadline("d", 4, time.Now().Add(-time.Second))
+
+	path := filepath.Join(t.TempDir(), "cache.snap")
+	if err := cache.SaveToFile(path); err != nil {
+		t.Fatalf("SaveToFile failed: %v", err)
+	}
+
+	restored := Cache{}
+	restored.Init()
+	n, err := restored.LoadFromFile(path)
+	if err != nil || n != 3 {
+		t.Fatalf("LoadFromFile returned %d, %v", n, err)
+	}
+
+	if restored.Get("a") != 1 || restored.Get("b") != "x" ||
+		string(restored.Get("c").([]byte)) != "y" || restored.Exists("d") {
+		t.Errorf("Snapshot not restored correctly")
+	}
+	if d, _ := restored.TTL("a"); d < 58*time.Second {
+		t.Errorf("Restored key has a TTL of %v", d)
+	}
+	if d, _ := restored.TTL("c"); d != NoExpiry {
+		t.Errorf("Restored key without expiry has a TTL of %v", d)
+	}
+	if m, _ := restored.Metadata("b"); m["owner"] != "me" {
+		t.Errorf("Restored metadata %v", m)
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Put(key, key)
+	}
+
+	var buf bytes.Buffer
+	if err := cache.Save(&buf); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	// drop the end, corrupt the last byte of the second record, and not a
+	// snapshot at all
+	size := (len(data) - 6 - 16) / 3
+	corrupt := append([]byte{}, data...)
+	corrupt[6+2*size-5] ^= 1
+
+	tests := []struct {
+		data []byte
+		n    int
+	}{
+		{data[:len(data)-16], 3},
+		{data[:6+size+3], 1},
+		{corrupt, 1},
+		{[]byte("junk"), 0},
+	}
+
+	for i, test := range tests {
+		restored := Cache{}
+		restored.Init()
+
+		n, err := restored.Load(bytes.NewReader(test.data))
+		if !errors.Is(err, ErrSnapshot) || n != test.n ||
+			restored.Count() != test.n {
+			t.Errorf("Test %d: Load returned %d, %v with %d keys", i, n, err,
+				restored.Count())
+		}
+	}
+}
+
+func TestSnapshotCorruptLength(t *testing.T) {
+	// a record claiming to be 1GB long in a snapshot of a few bytes
+	data := []byte(snapshotMagic + "\x00\x01\x3f\xff\xff\xffabc")
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	cache := Cache{}
+	cache.Init()
+	if _, err := cache.Load(bytes.NewReader(data)); !errors.Is(err,
+		ErrSnapshot) {
+		t.Errorf("Load returned %v", err)
+	}
+
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+		t.Errorf("Load allocated %d bytes for a corrupt length", n)
+	}
+}
+
+func FuzzLoad(f *testing.F) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+	cache.Put("a", 1)
+	cache.PutWithMetadata("b", "x", map[string]string{"owner": "me"})
+	cache.PutWithExpiry("c", []byte("y"), 0)
+
+	var buf bytes.Buffer
+	if err := cache.Save(&buf); err != nil {
+		f.Fatalf("Save failed: %v", err)
+	}
+	f.Add(buf.Bytes())
+	f.Add(buf.Bytes()[:buf.Len()/2])
+	f.Add([]byte("junk"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		restored := Cache{}
+		restored.Init()
+
+		n, err := restored.Load(bytes.NewReader(data))
+		if err != nil && !errors.Is(err, ErrSnapshot) {
+			t.Errorf("Load returned %v", err)
+		}
+		if restored.Count() > n {
+			t.Errorf("Load returned %d with %d keys", n, restored.Count())
+		}
+	})
+}
@@ -0,0 +1,132 @@
+// Package sqlcache adds cache-aside read caching to database/sql queries
+// using an expiringcache.Cache.
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/deep-compute/expiringcache"
+	"strings"
+)
+
+// Rows is the fully read result of a query. Cached results are shared
+// between callers and must not be modified.
+type Rows struct {
+	Columns []string
+	Values  [][]interface{}
+}
+
+// DB wraps a *sql.DB, caching the results of queries run with Query
+type DB struct {
+	DB    *sql.DB
+	Cache *expiringcache.Cache
+
+	// AfterExec, if set, is called after each successful Exec so related
+	// cached queries can be dropped with Invalidate.
+	AfterExec func(db *DB, query string, args []interface{})
+}
+
+// Key returns the cache key of query with args. Queries differing only in
+// whitespace share a key.
+func Key(query string, args ...interface{}) string {
+	var b strings.Builder
+	b.WriteString("sql:")
+	b.WriteString(strings.Join(strings.Fields(query), " "))
+	for _, a := range args {
+		// include the type so that e.g. 1 and "1" don't collide
+		fmt.Fprintf(&b, "\x00%T:%v", a, a)
+	}
+	return b.String()
+}
+
+// Query returns the rows of query, from the cache if possible. Results are
+// cached as with Cache.Put.
+func (p *DB) Query(ctx context.Context, query string,
+	args ...interface{}) (*Rows, error) {
+	return p.query(ctx, 0, query, args)
+}
+
+// QueryWithExpiry is like Query but caches the results for the given number
+// of seconds.
+func (p *DB) QueryWithExpiry(ctx context.Context, duration int, query string,
+	args ...interface{}) (*Rows, error) {
+	return p.query(ctx, duration, query, args)
+}
+
+func (p *DB) query(ctx context.Context, duration int, query string,
+	args []interface{}) (*Rows, error) {
+	key := Key(query, args...)
+	if rows, ok := p.Cache.Get(key).(*Rows); ok {
+		return rows, nil
+	}
+
+	rows, err := p.load(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if duration > 0 {
+		err = p.Cache.SetWithExpiry(key, rows, duration)
+	} else {
+		err = p.Cache.Set(key, rows)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (p *DB) load(ctx context.Context, query string,
+	args []interface{}) (*Rows, error) {
+	rs, err := p.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	cols, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &Rows{Columns: cols}
+	for rs.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+
+		// scanning into *interface{} copies []byte values
+		if err := rs.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		rows.Values = append(rows.Values, values)
+	}
+
+	return rows, rs.Err()
+}
+
+// Exec runs query without caching and then calls AfterExec
+func (p *DB) Exec(ctx context.Context, query string,
+	args ...interface{}) (sql.Result, error) {
+	res, err := p.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.AfterExec != nil {
+		p.AfterExec(p, query, args)
+	}
+
+	return res, nil
+}
+
+// Invalidate drops the cached results of query with args
+func (p *DB) Invalidate(query string, args ...interface{}) {
+	p.Cache.Del(Key(query, args...))
+}
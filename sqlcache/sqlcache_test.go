@@ -0,0 +1,93 @@
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/deep-compute/expiringcache"
+	"io"
+	"testing"
+)
+
+// fakeDriver answers every query with a single row holding the number of
+// queries run so far
+type fakeDriver struct{ queries int }
+
+type fakeConn struct{ d *fakeDriver }
+
+type fakeStmt struct{ d *fakeDriver }
+
+type fakeRows struct {
+	n    int64
+	done bool
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+// fakeDriver is its own connector so tests don't register it globally
+func (d *fakeDriver) Connect(ctx context.Context) (driver.Conn, error) { return &fakeConn{d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                            { return d }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.queries++
+	return &fakeRows{n: int64(s.d.queries)}, nil
+}
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.n
+	return nil
+}
+
+func TestQuery(t *testing.T) {
+	sqldb := sql.OpenDB(&fakeDriver{})
+	defer sqldb.Close()
+
+	cache := expiringcache.Cache{Duration: 60}
+	cache.Init()
+
+	db := &DB{DB: sqldb, Cache: &cache,
+		AfterExec: func(db *DB, query string, args []interface{}) {
+			db.Invalidate("SELECT n FROM t WHERE id = ?", 1)
+		}}
+
+	ctx := context.Background()
+	query := func(q string, args ...interface{}) int64 {
+		rows, err := db.Query(ctx, q, args...)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		return rows.Values[0][0].(int64)
+	}
+
+	if query("SELECT n FROM t WHERE id = ?", 1) != 1 ||
+		query("SELECT n\n  FROM t WHERE id = ?", 1) != 1 {
+		t.Errorf("Normalized query not served from cache")
+	}
+
+	if query("SELECT n FROM t WHERE id = ?", "1") != 2 {
+		t.Errorf("Arguments of different types share a cache key")
+	}
+
+	db.Exec(ctx, "UPDATE t SET n = n + 1 WHERE id = ?", 1)
+	if query("SELECT n FROM t WHERE id = ?", 1) != 3 {
+		t.Errorf("AfterExec hook did not invalidate query")
+	}
+}
@@ -0,0 +1,37 @@
+package expiringcache
+
+// stale reports whether the key's soft expiry has passed at time ts
+func (p *CacheValue) stale(ts int64) bool {
+	return p.StaleAt != 0 && p.StaleAt <= ts
+}
+
+// PutWithSoftExpiry stores key with two expiry durations in seconds: after
+// soft the key is stale, which GetStale reports and which makes a Get
+// reload it in the background if a Loader is set, and after hard it expires
+// as with PutWithExpiry.
+func (p *Cache) PutWithSoftExpiry(key string, value interface{}, soft int,
+	hard int) error {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	_, _, err = p.put(key, value, entryOptions{duration: hard, soft: soft})
+	p.Unlock()
+	return err
+}
+
+// GetStale is like Get but also reports whether the key is stale, i.e.
+// past its soft expiry.
+func (p *Cache) GetStale(key string) (interface{}, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	cv := p.access(key)
+	if cv == nil {
+		return nil, false
+	}
+
+	return p.clone(cv.Value), cv.stale(p.now())
+}
@@ -0,0 +1,48 @@
+package expiringcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSoftExpiry(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.PutWithSoftExpiry("a", 1, 60, 120)
+	if _, stale := cache.GetStale("a"); stale {
+		t.Errorf("Key stale before soft expiry")
+	}
+
+	cache.PutWithSoftExpiry("b", 1, -1, 120)
+	if v, stale := cache.GetStale("b"); !stale || v.(int) != 1 {
+		t.Errorf("Key not returned as stale after soft expiry")
+	}
+}
+
+func TestStaleRefresh(t *testing.T) {
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			return 2, nil
+		}}
+	cache.Init()
+
+	events, cancel := cache.Subscribe()
+	defer cancel()
+
+	cache.PutWithSoftExpiry("a", 1, -1, 120)
+	<-events
+
+	cache.Get("a")
+	select {
+	case <-events:
+	case <-time.After(time.Second):
+		t.Fatalf("Stale key not refreshed in the background")
+	}
+
+	if v, stale := cache.GetStale("a"); v.(int) != 2 || !stale {
+		// the soft duration is kept across refreshes
+		t.Errorf("Refresh returned %v, stale %v", v, stale)
+	}
+}
@@ -0,0 +1,103 @@
+package expiringcache
+
+import (
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentStress runs every kind of operation at once so that
+// go test -race can find unsynchronized access
+func TestConcurrentStress(t *testing.T) {
+	cache := Cache{Duration: 1, Max: 200, NEvictions: 10, NSamples: 5,
+		PeriodicEvictionInterval: 1, SweepBatchSize: 16, HotKeys: 8,
+		RandSource: rand.NewSource(1), TTLJitter: 0.2}
+	cache.Init()
+	defer cache.Close()
+
+	events, cancel := cache.Subscribe()
+	go func() {
+		for range events {
+		}
+	}()
+	defer cancel()
+
+	ops := []func(key string, i int){
+		func(key string, i int) { cache.Put(key, i) },
+		func(key string, i int) { cache.PutWithExpiry(key, i, -1) },
+		func(key string, i int) { cache.Get(key) },
+		func(key string, i int) { cache.GetEntry(key) },
+		func(key string, i int) { cache.Del(key) },
+		func(key string, i int) { cache.Pop(key) },
+		func(key string, i int) { cache.Exists(key) },
+		func(key string, i int) { cache.PopRandom() },
+		func(key string, i int) { cache.PeekRandom() },
+		func(key string, i int) { cache.TTL(key) },
+		func(key string, i int) { cache.EvictExpired(10, 0) },
+		func(key string, i int) {
+			cache.Update(key, func(old interface{}, ok bool) (
+				interface{}, bool) {
+				return i, true
+			})
+		},
+		func(key string, i int) {
+			for range cache.Iter() {
+			}
+		},
+		func(key string, i int) { cache.TopKeys(3) },
+		func(key string, i int) { cache.ExpiryHistogram(4) },
+		func(key string, i int) { cache.ExpiringWithin(time.Second) },
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < 2000; i++ {
+				key := strconv.Itoa(r.Intn(300))
+				ops[r.Intn(len(ops))](key, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if n := cache.Count(); n > 200 {
+		t.Errorf("Cache holds %d keys, more than Max", n)
+	}
+}
+
+func TestBytesCacheConcurrentStress(t *testing.T) {
+	cache := BytesCache{Duration: 60, ArenaSize: 4096}
+	cache.Init()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < 2000; i++ {
+				key := strconv.Itoa(r.Intn(100))
+				switch r.Intn(4) {
+				case 0:
+					cache.Put(key, []byte(key))
+				case 1:
+					if v := cache.Get(key); v != nil && string(v) != key {
+						t.Errorf("Get(%s) returned %s", key, v)
+					}
+				case 2:
+					cache.Del(key)
+				case 3:
+					cache.Count()
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
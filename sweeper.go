@@ -0,0 +1,147 @@
+package expiringcache
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Sweeper runs the periodic eviction of many caches on a fixed pool of
+// goroutines, instead of one goroutine per cache. Caches use it when it is
+// set as their Sweeper, every PeriodicEvictionInterval seconds (or as set
+// by SetSweepInterval) as usual.
+type Sweeper struct {
+	Workers int // goroutines sweeping caches, defaults to GOMAXPROCS
+
+	caches map[*Cache]*sweep
+	wake   chan struct{}
+	work   chan *Cache
+	stop   chan struct{}
+	sync.Mutex
+}
+
+// sweep is when a cache is next swept
+type sweep struct {
+	next time.Time
+	busy bool // being swept by a worker
+}
+
+func (s *Sweeper) Init() {
+	s.caches = make(map[*Cache]*sweep)
+	s.wake = make(chan struct{}, 1)
+	s.work = make(chan *Cache)
+	s.stop = make(chan struct{})
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	for i := 0; i < workers; i++ {
+		go s.worker()
+	}
+	go s.schedule()
+}
+
+// Close stops all sweeps. Caches using the sweeper are no longer swept.
+func (s *Sweeper) Close() {
+	close(s.stop)
+}
+
+// add schedules cache to be swept one interval from now
+func (s *Sweeper) add(cache *Cache) {
+	next := time.Now().Add(cache.sweepInterval())
+
+	s.Lock()
+	if sw, ok := s.caches[cache]; ok {
+		sw.next = next
+	} else {
+		s.caches[cache] = &sweep{next: next}
+	}
+	s.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// remove stops sweeping cache
+func (s *Sweeper) remove(cache *Cache) {
+	s.Lock()
+	delete(s.caches, cache)
+	s.Unlock()
+}
+
+// schedule hands each cache to a worker when its sweep is due
+func (s *Sweeper) schedule() {
+	for {
+		var due []*Cache
+		var next time.Time
+
+		s.Lock()
+		now := time.Now()
+		for cache, sw := range s.caches {
+			if sw.busy || cache.sweepInterval() <= 0 {
+				continue
+			}
+			if !sw.next.After(now) {
+				sw.busy = true
+				due = append(due, cache)
+			} else if next.IsZero() || sw.next.Before(next) {
+				next = sw.next
+			}
+		}
+		s.Unlock()
+
+		for _, cache := range due {
+			select {
+			case s.work <- cache:
+			case <-s.stop:
+				return
+			}
+		}
+		if len(due) > 0 {
+			continue
+		}
+
+		// with nothing scheduled, wait until a cache is added
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		case <-s.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (s *Sweeper) worker() {
+	for {
+		select {
+		case cache := <-s.work:
+			cache.evictExpired()
+
+			s.Lock()
+			if sw, ok := s.caches[cache]; ok {
+				sw.next = time.Now().Add(cache.sweepInterval())
+				sw.busy = false
+			}
+			s.Unlock()
+
+			// the cache may now be the next one due
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
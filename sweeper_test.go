@@ -0,0 +1,85 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSweeper(t *testing.T) {
+	s := Sweeper{Workers: 2}
+	s.Init()
+	defer s.Close()
+
+	var caches []*Cache
+	for i := 0; i < 5; i++ {
+		cache := &Cache{PeriodicEvictionInterval: 1, Sweeper: &s}
+		cache.Init()
+		cache.PutWithExpiry("a", 1, 1)
+		cache.Put("b", 2)
+		caches = append(caches, cache)
+	}
+
+	time.Sleep(3 * time.Second)
+
+	for i, cache := range caches {
+		cache.Lock()
+		swept := cache.data.Find(&CacheValue{Key: "a"}) == nil
+		cache.Unlock()
+		if !swept {
+			t.Errorf("Cache %d wasn't swept", i)
+		}
+		if !cache.Exists("b") {
+			t.Errorf("Cache %d lost a key that hadn't expired", i)
+		}
+	}
+
+	caches[0].Close()
+	s.Lock()
+	n := len(s.caches)
+	s.Unlock()
+	if n != 4 {
+		t.Errorf("Closed cache still swept, %d caches scheduled", n)
+	}
+}
+
+func TestSweepInterval(t *testing.T) {
+	cache := &Cache{}
+	cache.Init()
+	defer cache.Close()
+
+	cache.PutWithDeadline("a", 1, time.Now().Add(-time.Second))
+	cache.SetSweepInterval(10 * time.Millisecond)
+	eventually(t, "the sweep", func() bool { return cache.Count() == 0 })
+
+	cache.PauseSweeper()
+	time.Sleep(20 * time.Millisecond)
+	cache.PutWithDeadline("b", 1, time.Now().Add(-time.Second))
+	time.Sleep(50 * time.Millisecond)
+	if cache.Count() != 1 {
+		t.Errorf("Paused sweeper removed keys")
+	}
+
+	cache.ResumeSweeper()
+	eventually(t, "the resumed sweep", func() bool { return cache.Count() == 0 })
+
+	cache.SetSweepInterval(0)
+	time.Sleep(20 * time.Millisecond)
+	cache.PutWithDeadline("c", 1, time.Now().Add(-time.Second))
+	time.Sleep(50 * time.Millisecond)
+	if cache.Count() != 1 {
+		t.Errorf("Disabled sweeper removed keys")
+	}
+}
+
+func TestSweeperInterval(t *testing.T) {
+	s := Sweeper{Workers: 1}
+	s.Init()
+	defer s.Close()
+
+	cache := &Cache{Sweeper: &s}
+	cache.Init()
+	cache.PutWithDeadline("a", 1, time.Now().Add(-time.Second))
+
+	cache.SetSweepInterval(10 * time.Millisecond)
+	eventually(t, "the shared sweep", func() bool { return cache.Count() == 0 })
+}
@@ -0,0 +1,110 @@
+package expiringcache
+
+import "time"
+
+// scheduleExpiry starts the timer removing cv when it expires if
+// ExactExpiry is set, replacing any timer it had. It must be called with
+// the lock held whenever the expiry of cv changes.
+func (p *Cache) scheduleExpiry(cv *CacheValue) {
+	if !p.ExactExpiry {
+		return
+	}
+
+	p.stopExpiry(cv)
+	if cv.ExpireAt == 0 {
+		return
+	}
+	if p.ExpiryGranularity > 1 {
+		p.addToBucket(cv)
+		return
+	}
+
+	d := time.Unix(cv.ExpireAt, 0).Sub(p.clock())
+	cv.timer = time.AfterFunc(d, func() {
+		p.Lock()
+		defer p.Unlock()
+
+		// the key may have been removed or replaced since
+		v := p.find(cv.Key)
+		if v == cv && cv.expired(p.now()) {
+			p.remove(cv, EventExpire)
+		}
+	})
+}
+
+// stopExpiry stops the timer of cv, if any
+func (p *Cache) stopExpiry(cv *CacheValue) {
+	if cv.timer != nil {
+		cv.timer.Stop()
+		cv.timer = nil
+	}
+
+	if cv.bucket == 0 {
+		return
+	}
+	if b := p.buckets[cv.bucket]; b != nil {
+		delete(b.entries, cv)
+		if len(b.entries) == 0 {
+			b.timer.Stop()
+			delete(p.buckets, cv.bucket)
+		}
+	}
+	cv.bucket = 0
+}
+
+// expiryBucket holds the keys expiring at the same time with
+// ExpiryGranularity, removed together by one timer
+type expiryBucket struct {
+	timer   *time.Timer
+	entries map[*CacheValue]struct{}
+}
+
+// quantize rounds expireAt up to a multiple of ExpiryGranularity
+func (p *Cache) quantize(expireAt int64) int64 {
+	b := int64(p.ExpiryGranularity)
+	if b <= 1 || expireAt == 0 {
+		return expireAt
+	}
+	return (expireAt + b - 1) / b * b
+}
+
+// addToBucket adds cv to the bucket of its expiry, starting the timer of
+// the bucket if it is new
+func (p *Cache) addToBucket(cv *CacheValue) {
+	at := cv.ExpireAt
+	b := p.buckets[at]
+	if b == nil {
+		if p.buckets == nil {
+			p.buckets = make(map[int64]*expiryBucket)
+		}
+
+		b = &expiryBucket{entries: make(map[*CacheValue]struct{})}
+		d := time.Unix(at, 0).Sub(p.clock())
+		b.timer = time.AfterFunc(d, func() { p.expireBucket(at, b) })
+		p.buckets[at] = b
+	}
+
+	b.entries[cv] = struct{}{}
+	cv.bucket = at
+}
+
+// expireBucket removes the keys of the bucket b expiring at at
+func (p *Cache) expireBucket(at int64, b *expiryBucket) {
+	p.Lock()
+	defer p.Unlock()
+
+	// the bucket may have emptied and been replaced since
+	if p.buckets[at] != b {
+		return
+	}
+	delete(p.buckets, at)
+
+	// as with the timer of a single key, keys that haven't expired by
+	// the time of the cache are left to the periodic eviction
+	ts := p.now()
+	for cv := range b.entries {
+		if cv.expired(ts) && p.find(cv.Key) == cv {
+			p.remove(cv, EventExpire)
+		}
+	}
+}
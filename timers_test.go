@@ -0,0 +1,72 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExactExpiry(t *testing.T) {
+	cache := Cache{ExactExpiry: true}
+	cache.Init()
+	events, _ := cache.Subscribe()
+
+	cache.PutWithExpiry("a", 1, 1)
+	cache.PutWithExpiry("b", 2, 1)
+	cache.PutWithExpiry("b", 2, 60)
+	cache.Put("c", 3)
+
+	<-events
+	<-events
+	<-events
+	<-events
+
+	select {
+	case e := <-events:
+		if e.Type != EventExpire || e.Key != "a" {
+			t.Errorf("Unexpected %v event for %s", e.Type, e.Key)
+		}
+	case <-time.After(2500 * time.Millisecond):
+		t.Fatalf("Key not removed when it expired")
+	}
+
+	if cache.Count() != 2 || !cache.Exists("b") {
+		t.Errorf("Keys that haven't expired were removed")
+	}
+
+	cache.Lock()
+	stopped := cache.data.Find(&CacheValue{Key: "c"}).(*CacheValue).timer == nil
+	cache.Unlock()
+	if !stopped {
+		t.Errorf("Key without expiry has a timer")
+	}
+}
+
+func TestExpiryGranularity(t *testing.T) {
+	cache := Cache{ExactExpiry: true, ExpiryGranularity: 2}
+	cache.Init()
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.PutWithExpiry(key, 1, 1)
+	}
+	cache.PutWithExpiry("d", 1, 60)
+
+	if at, _ := cache.ExpiresAt("a"); at.Unix()%2 != 0 {
+		t.Errorf("Expiry %v not rounded to the bucket", at)
+	}
+	if d := cache.Diagnostics(); d.ExpiryTimers != 2 {
+		t.Errorf("%d timers, expected one per bucket", d.ExpiryTimers)
+	}
+
+	cache.Del("d")
+	if d := cache.Diagnostics(); d.ExpiryTimers != 1 {
+		t.Errorf("Timer of an empty bucket kept")
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for cache.Count() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if cache.Count() != 0 {
+		t.Errorf("Bucket did not expire its keys")
+	}
+}
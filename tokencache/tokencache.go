@@ -0,0 +1,78 @@
+// Package tokencache caches OAuth/JWT style access tokens until they
+// expire, refreshing them in the background shortly before they do so
+// callers always get a valid token without waiting.
+package tokencache
+
+import (
+	"context"
+	"github.com/deep-compute/expiringcache"
+	"strings"
+	"time"
+)
+
+// Token is an access token valid until Expiry
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+// RefreshFunc obtains a new token for audience and scope
+type RefreshFunc func(ctx context.Context, audience, scope string) (Token, error)
+
+// Cache holds tokens keyed by audience and scope
+type Cache struct {
+	cache   expiringcache.Cache
+	refresh RefreshFunc
+}
+
+// New returns a Cache obtaining tokens with refresh. Once refreshAhead of
+// a token's lifetime has passed (e.g. 0.8 for 80%) the next request for it
+// refreshes it in the background.
+func New(refresh RefreshFunc, refreshAhead float64) *Cache {
+	p := &Cache{refresh: refresh}
+	p.cache = expiringcache.Cache{
+		Duration:     1,
+		RefreshAhead: refreshAhead,
+		Loader:       p.load,
+		TTLFunc: func(key string, value interface{}) time.Duration {
+			return time.Until(value.(Token).Expiry)
+		},
+	}
+	p.cache.Init()
+	return p
+}
+
+func key(audience, scope string) string {
+	return audience + "\x00" + scope
+}
+
+func (p *Cache) load(ctx context.Context, key string) (interface{}, error) {
+	audience, scope, _ := strings.Cut(key, "\x00")
+	return p.refresh(ctx, audience, scope)
+}
+
+// Token returns a valid token for audience and scope, obtaining one if
+// there is none cached or the cached one has expired.
+func (p *Cache) Token(ctx context.Context, audience, scope string) (Token, error) {
+	k := key(audience, scope)
+	for i := 0; ; i++ {
+		v, err := p.cache.GetCtx(ctx, k)
+		if err != nil {
+			return Token{}, err
+		}
+
+		t := v.(Token)
+		if time.Now().Before(t.Expiry) || i > 0 {
+			return t, nil
+		}
+
+		// expired without a successful background refresh
+		p.cache.Del(k)
+	}
+}
+
+// Invalidate drops the token for audience and scope, e.g. after it was
+// rejected
+func (p *Cache) Invalidate(audience, scope string) {
+	p.cache.Del(key(audience, scope))
+}
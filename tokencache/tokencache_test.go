@@ -0,0 +1,54 @@
+package tokencache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	calls := 0
+	expiries := []time.Duration{-time.Second, time.Hour}
+	cache := New(func(ctx context.Context, audience, scope string) (Token, error) {
+		calls++
+		return Token{Value: audience + ":" + scope + ":" + strconv.Itoa(calls),
+			Expiry: time.Now().Add(expiries[calls-1])}, nil
+	}, 0.8)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		// the first token obtained has already expired
+		tok, err := cache.Token(ctx, "api", "read")
+		if err != nil || tok.Value != "api:read:2" {
+			t.Errorf("Token returned %v, %v", tok, err)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("Token refreshed %d times, expected 2", calls)
+	}
+}
+
+func TestTokenRefreshAhead(t *testing.T) {
+	refreshed := make(chan string, 2)
+	cache := New(func(ctx context.Context, audience, scope string) (Token, error) {
+		refreshed <- audience
+		return Token{Value: audience, Expiry: time.Now().Add(2 * time.Second)}, nil
+	}, 0.5)
+
+	ctx := context.Background()
+	cache.Token(ctx, "api", "read")
+	<-refreshed
+
+	time.Sleep(1100 * time.Millisecond)
+	if tok, _ := cache.Token(ctx, "api", "read"); !tok.Expiry.After(time.Now()) {
+		t.Errorf("Expired token returned")
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Errorf("Token not refreshed ahead of expiry")
+	}
+}
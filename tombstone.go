@@ -0,0 +1,76 @@
+package expiringcache
+
+import (
+	"time"
+)
+
+// tombstoned reports whether key was deleted less than TombstoneDuration
+// ago, dropping the tombstone once it has expired. It must be called with
+// the lock held.
+func (p *Cache) tombstoned(key string) bool {
+	until, ok := p.tombstones[key]
+	if !ok {
+		return false
+	}
+	if until > p.now() {
+		return true
+	}
+
+	delete(p.tombstones, key)
+	return false
+}
+
+// bury leaves a tombstone for key, which was just deleted, if
+// TombstoneDuration is set. It must be called with the lock held.
+func (p *Cache) bury(key string) {
+	if p.TombstoneDuration <= 0 {
+		return
+	}
+
+	if p.tombstones == nil {
+		p.tombstones = make(map[string]int64)
+	}
+	p.tombstones[key] = p.now() + int64(p.TombstoneDuration)
+}
+
+// purgeTombstones drops the expired tombstones
+func (p *Cache) purgeTombstones() {
+	p.Lock()
+	defer p.Unlock()
+
+	ts := p.now()
+	for key, until := range p.tombstones {
+		if until <= ts {
+			delete(p.tombstones, key)
+		}
+	}
+}
+
+// putLoaded stores a value Loader returned for a missing key as with Put,
+// unless the key was written meanwhile, which is newer, or has a
+// tombstone, so a load that raced with Del doesn't bring back what was
+// just deleted
+func (p *Cache) putLoaded(key string, value interface{}) error {
+	if p.latency != nil {
+		defer p.observe(&p.latency.put, time.Now())
+	}
+
+	put := func(key string, value interface{}) error {
+		value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+		if err != nil {
+			return err
+		}
+
+		p.Lock()
+		o := p.defaultOptions(key, value)
+		o.loaded = true
+		_, _, err = p.put(key, value, o)
+		p.Unlock()
+		return err
+	}
+
+	if len(p.middleware) > 0 {
+		return p.chain(Handler{Get: p.lookup, Put: put}).Put(key, value)
+	}
+	return put(key, value)
+}
@@ -0,0 +1,84 @@
+package expiringcache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTombstone(t *testing.T) {
+	now := time.Unix(1000, 0)
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	cache := Cache{Duration: 60, TombstoneDuration: 5,
+		Clock: func() time.Time { return now },
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			close(loading)
+			<-release
+			return "stale", nil
+		}}
+	cache.Init()
+
+	done := make(chan interface{})
+	go func() {
+		v, _ := cache.GetCtx(context.Background(), "a")
+		done <- v
+	}()
+
+	// the key is deleted while it is being loaded
+	<-loading
+	cache.Put("a", "fresh")
+	cache.Del("a")
+	close(release)
+
+	if v := <-done; v != "stale" {
+		t.Errorf("GetCtx returned %v", v)
+	}
+	if cache.Exists("a") {
+		t.Errorf("Load in flight stored a deleted key")
+	}
+
+	cache.Put("a", "new")
+	if v := cache.Get("a"); v != "new" {
+		t.Errorf("Put ignored after Del: %v", v)
+	}
+
+	cache.Del("a")
+	now = now.Add(10 * time.Second)
+	cache.Lock()
+	tombstoned := cache.tombstoned("a")
+	cache.Unlock()
+	if tombstoned {
+		t.Errorf("Tombstone outlived TombstoneDuration")
+	}
+}
+
+func TestLoadKeepsNewerWrite(t *testing.T) {
+	loading := make(chan struct{})
+	release := make(chan struct{})
+	cache := Cache{Duration: 60,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			close(loading)
+			<-release
+			return "stale", nil
+		}}
+	cache.Init()
+
+	done := make(chan interface{})
+	go func() {
+		v, _ := cache.GetCtx(context.Background(), "a")
+		done <- v
+	}()
+
+	// the key is written while it is being loaded
+	<-loading
+	cache.Put("a", "fresh")
+	close(release)
+
+	if v := <-done; v != "stale" {
+		t.Errorf("GetCtx returned %v", v)
+	}
+	if v := cache.Get("a"); v != "fresh" {
+		t.Errorf("Load in flight replaced a newer write with %v", v)
+	}
+}
@@ -0,0 +1,24 @@
+package expiringcache
+
+import (
+	"context"
+)
+
+// Tracer records cache operations, e.g. as spans in distributed traces
+// (see the otelcache package). Start is called when op, one of "get",
+// "put" or "load", begins on key and returns the function to call when it
+// ends, with whether a get found the key and the error returned, if any.
+// Loads of several keys with BatchLoader have an empty key.
+type Tracer interface {
+	Start(ctx context.Context, op string, key string) (context.Context,
+		func(hit bool, err error))
+}
+
+// trace starts tracing op if a Tracer is set
+func (p *Cache) trace(ctx context.Context, op string,
+	key string) (context.Context, func(hit bool, err error)) {
+	if p.Tracer == nil {
+		return ctx, func(bool, error) {}
+	}
+	return p.Tracer.Start(ctx, op, key)
+}
@@ -0,0 +1,36 @@
+package expiringcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+type recordingTracer struct{ ops []string }
+
+func (p *recordingTracer) Start(ctx context.Context, op string,
+	key string) (context.Context, func(bool, error)) {
+	return ctx, func(hit bool, err error) {
+		p.ops = append(p.ops, fmt.Sprintf("%s %s %v %v", op, key, hit, err))
+	}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &recordingTracer{}
+	cache := Cache{Duration: 60, Tracer: tracer,
+		Loader: func(ctx context.Context, key string) (interface{}, error) {
+			return 1, nil
+		}}
+	cache.Init()
+
+	ctx := context.Background()
+	cache.GetCtx(ctx, "a")
+	cache.GetCtx(ctx, "a")
+	cache.PutCtx(ctx, "b", 2)
+
+	expected := "[get a false <nil> load a false <nil> get a true <nil> " +
+		"put b false <nil>]"
+	if fmt.Sprint(tracer.ops) != expected {
+		t.Errorf("Traced %v", tracer.ops)
+	}
+}
@@ -0,0 +1,208 @@
+package expiringcache
+
+import (
+	"sort"
+	"time"
+)
+
+// NoExpiry is the TTL of keys that never expire
+const NoExpiry time.Duration = -1
+
+// seconds returns d in whole seconds, rounding up so a sub-second duration
+// neither expires at once nor, as 0, never expires
+func seconds(d time.Duration) int {
+	if d > 0 {
+		return int((d + time.Second - 1) / time.Second)
+	}
+	return int(d / time.Second)
+}
+
+// ExpiresAt returns when key expires, and false if it is not in the cache.
+// The time is zero if the key never expires.
+func (p *Cache) ExpiresAt(key string) (time.Time, bool) {
+	p.Lock()
+	v := p.find(p.canonicalKey(key))
+	p.Unlock()
+
+	if v == nil {
+		return time.Time{}, false
+	}
+
+	if v.ExpireAt == 0 {
+		return time.Time{}, true
+	}
+
+	return time.Unix(v.ExpireAt, 0).UTC(), true
+}
+
+// TTL returns how long key has left before it expires, and false if it is
+// not in the cache. Keys that have expired but not yet been removed have a
+// TTL of 0 and keys that never expire a TTL of NoExpiry.
+func (p *Cache) TTL(key string) (time.Duration, bool) {
+	t, ok := p.ExpiresAt(key)
+	if !ok {
+		return 0, false
+	}
+
+	if t.IsZero() {
+		return NoExpiry, true
+	}
+
+	d := t.Sub(p.clock())
+	if d < 0 {
+		d = 0
+	}
+
+	return d, true
+}
+
+// PutWithDeadline stores key until the given deadline, e.g. the expiry of
+// a token, rather than for a duration. TTLJitter does not apply.
+func (p *Cache) PutWithDeadline(key string, value interface{},
+	deadline time.Time) error {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	p.Lock()
+	_, _, err = p.put(key, value, entryOptions{expireAt: deadline.Unix()})
+	p.Unlock()
+	return err
+}
+
+// ExpiringWithin returns copies of the entries that expire within d, soonest
+// first, so they can be refreshed or saved before they are gone. Entries
+// that have expired but not yet been removed are included.
+func (p *Cache) ExpiringWithin(d time.Duration) []CacheValue {
+	until := p.clock().Add(d).Unix()
+
+	var entries []CacheValue
+
+	p.Lock()
+	for i := 0; i < p.data.Len(); i++ {
+		cv := p.data.At(i).(*CacheValue)
+		if cv.ExpireAt != 0 && cv.ExpireAt <= until {
+			e := *cv
+			e.Value = p.clone(e.Value)
+			e.Metadata = copyMetadata(e.Metadata)
+			entries = append(entries, e)
+		}
+	}
+	p.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ExpireAt < entries[j].ExpireAt
+	})
+	return entries
+}
+
+// TouchMany restarts the expiry of each of keys in one pass, storing them
+// again for the duration they were last stored for, e.g. to keep sessions
+// alive. Keys that are missing, expired or never expire are skipped. It
+// returns the number of keys touched.
+func (p *Cache) TouchMany(keys []string) int {
+	p.Lock()
+	defer p.Unlock()
+
+	ts := p.now()
+	n := 0
+	for _, key := range keys {
+		cv := p.find(p.canonicalKey(key))
+		if cv == nil {
+			continue
+		}
+
+		if cv.ExpireAt == 0 || cv.expired(ts) {
+			continue
+		}
+
+		cv.ExpireAt = p.quantize(ts + cv.ttl)
+		p.scheduleExpiry(cv)
+		n++
+	}
+
+	return n
+}
+
+// ExpireMany sets each of keys to expire ttl from now in one pass, within
+// MinDuration and MaxDuration. Keys that are missing or expired are
+// skipped. It returns the number of keys changed, or ErrDuration without
+// changing any with RejectDuration if ttl is out of bounds.
+func (p *Cache) ExpireMany(keys []string, ttl time.Duration) (int, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	ts := p.now()
+	expireAt, err := p.boundExpiry(ts, ts+int64(seconds(ttl)))
+	if err != nil {
+		return 0, err
+	}
+	expireAt = p.quantize(expireAt)
+
+	n := 0
+	for _, key := range keys {
+		cv := p.find(p.canonicalKey(key))
+		if cv == nil {
+			continue
+		}
+
+		if cv.expired(ts) {
+			continue
+		}
+
+		cv.ExpireAt = expireAt
+		p.scheduleExpiry(cv)
+		cv.ttl = expireAt - ts
+		cv.duration = cv.ttl
+		n++
+	}
+
+	return n, nil
+}
+
+// DeleteOlderThan removes the keys first added before t, e.g. to reclaim
+// memory in an emergency, and returns the number removed. Times are
+// compared to the second.
+func (p *Cache) DeleteOlderThan(t time.Time) int {
+	before := t.Unix()
+	return p.deleteWhere(func(cv *CacheValue) bool {
+		return cv.CreatedAt < before
+	})
+}
+
+// DeleteExpiringBefore removes the keys that expire before t, including
+// those that have expired but not been removed yet, and returns the number
+// removed. Keys that never expire are kept.
+func (p *Cache) DeleteExpiringBefore(t time.Time) int {
+	before := t.Unix()
+	return p.deleteWhere(func(cv *CacheValue) bool {
+		return cv.ExpireAt != 0 && cv.ExpireAt < before
+	})
+}
+
+// deleteWhere removes the keys matching match in one pass with EventDelete
+func (p *Cache) deleteWhere(match func(cv *CacheValue) bool) int {
+	p.Lock()
+	defer p.Unlock()
+
+	var matched []string
+	for i := 0; i < p.data.Len(); i++ {
+		cv := p.data.At(i).(*CacheValue)
+		if match(cv) {
+			matched = append(matched, cv.Key)
+		}
+	}
+
+	// removing a key may remove its dependents too, so look each one up
+	// again rather than remove by position
+	n := 0
+	for _, key := range matched {
+		if v := p.find(key); v != nil {
+			p.remove(v, EventDelete)
+			n++
+		}
+	}
+
+	return n
+}
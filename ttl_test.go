@@ -0,0 +1,177 @@
+package expiringcache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTL(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.PutWithDeadline("b", 1, time.Now().Add(-time.Second))
+
+	if d, ok := cache.TTL("a"); !ok || d <= 58*time.Second || d > time.Minute {
+		t.Errorf("TTL returned %v, %v", d, ok)
+	}
+
+	if d, ok := cache.TTL("b"); !ok || d != 0 {
+		t.Errorf("TTL of expired key returned %v, %v", d, ok)
+	}
+
+	at, ok := cache.ExpiresAt("a")
+	if !ok || at.Sub(time.Now()) > time.Minute {
+		t.Errorf("ExpiresAt returned %v, %v", at, ok)
+	}
+
+	if _, ok := cache.TTL("c"); ok {
+		t.Errorf("TTL found missing key")
+	}
+}
+
+func TestNoExpiry(t *testing.T) {
+	cache := Cache{}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.PutWithExpiry("b", 2, 60)
+
+	if d, ok := cache.TTL("a"); !ok || d != NoExpiry {
+		t.Errorf("TTL of key without expiry returned %v, %v", d, ok)
+	}
+
+	if at, ok := cache.ExpiresAt("a"); !ok || !at.IsZero() {
+		t.Errorf("ExpiresAt of key without expiry returned %v, %v", at, ok)
+	}
+
+	if n := cache.EvictExpired(0, 0); n != 0 || !cache.Exists("a") {
+		t.Errorf("Key without expiry was expired")
+	}
+
+	// keys that never expire are evicted last
+	a, _ := cache.GetEntry("a")
+	b, _ := cache.GetEntry("b")
+	if evictsBefore(a, b) {
+		t.Errorf("Key without expiry evicted before expiring key")
+	}
+}
+
+func TestPutWithDeadline(t *testing.T) {
+	cache := Cache{Duration: 60, TTLJitter: 0.5}
+	cache.Init()
+
+	deadline := time.Now().Add(time.Hour).Truncate(time.Second)
+	cache.PutWithDeadline("a", 1, deadline)
+
+	if at, _ := cache.ExpiresAt("a"); !at.Equal(deadline) {
+		t.Errorf("Key expires at %v, expected %v", at, deadline)
+	}
+}
+
+func TestExpiringWithin(t *testing.T) {
+	cache := Cache{}
+	cache.Init()
+
+	cache.PutWithExpiry("a", 1, 30)
+	cache.PutWithExpiry("b", 2, 10)
+	cache.PutWithExpiry("c", 3, 300)
+	cache.Put("d", 4)
+
+	entries := cache.ExpiringWithin(time.Minute)
+	if len(entries) != 2 || entries[0].Key != "b" || entries[1].Key != "a" {
+		t.Errorf("Unexpected entries expiring within a minute: %v", entries)
+	}
+}
+
+func TestTouchMany(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Duration: 60, Clock: func() time.Time { return now }}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.PutWithExpiry("b", 2, 10)
+	cache.PutWithExpiry("c", 3, 0)
+
+	now = now.Add(30 * time.Second)
+	if n := cache.TouchMany([]string{"a", "b", "c", "d"}); n != 1 {
+		t.Errorf("TouchMany touched %d keys, expected 1", n)
+	}
+
+	if d, _ := cache.TTL("a"); d != time.Minute {
+		t.Errorf("Touched key has a TTL of %v, expected 1m", d)
+	}
+	if d, _ := cache.TTL("c"); d != NoExpiry {
+		t.Errorf("Key without expiry has a TTL of %v after TouchMany", d)
+	}
+	if d, _ := cache.TTL("b"); d != 0 {
+		t.Errorf("Expired key was touched, TTL %v", d)
+	}
+}
+
+func TestExpireMany(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Duration: 60, MaxDuration: 120,
+		DurationPolicy: RejectDuration,
+		Clock:          func() time.Time { return now }}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.Put("b", 2)
+
+	n, err := cache.ExpireMany([]string{"a", "b", "c"}, 5*time.Second)
+	if err != nil || n != 2 {
+		t.Errorf("ExpireMany returned %d, %v", n, err)
+	}
+	if d, _ := cache.TTL("b"); d != 5*time.Second {
+		t.Errorf("Key has a TTL of %v, expected 5s", d)
+	}
+
+	if _, err := cache.ExpireMany([]string{"a"}, time.Hour); err != ErrDuration {
+		t.Errorf("ExpireMany over MaxDuration returned %v", err)
+	}
+	if d, _ := cache.TTL("a"); d != 5*time.Second {
+		t.Errorf("Rejected ExpireMany changed the TTL to %v", d)
+	}
+
+	// sub-second TTLs round up rather than expire the keys at once
+	cache.ExpireMany([]string{"a"}, 500*time.Millisecond)
+	if d, _ := cache.TTL("a"); d != time.Second {
+		t.Errorf("Sub-second ExpireMany left a TTL of %v", d)
+	}
+}
+
+func TestDeleteOlderThan(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Duration: 60, Clock: func() time.Time { return now }}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.PutWithExpiry("b", 2, 0)
+	now = now.Add(10 * time.Second)
+	cache.Put("c", 3)
+
+	if n := cache.DeleteOlderThan(now); n != 2 {
+		t.Errorf("DeleteOlderThan removed %d keys, expected 2", n)
+	}
+	if cache.Count() != 1 || !cache.Exists("c") {
+		t.Errorf("DeleteOlderThan removed the wrong keys")
+	}
+}
+
+func TestDeleteExpiringBefore(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := Cache{Duration: 60, Clock: func() time.Time { return now }}
+	cache.Init()
+
+	cache.PutWithExpiry("a", 1, 10)
+	cache.PutWithExpiry("b", 2, 100)
+	cache.PutWithExpiry("c", 3, 0)
+
+	if n := cache.DeleteExpiringBefore(now.Add(time.Minute)); n != 1 {
+		t.Errorf("DeleteExpiringBefore removed %d keys, expected 1", n)
+	}
+	if cache.Exists("a") || !cache.Exists("b") || !cache.Exists("c") {
+		t.Errorf("DeleteExpiringBefore removed the wrong keys")
+	}
+}
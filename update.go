@@ -0,0 +1,77 @@
+package expiringcache
+
+// Update atomically reads and writes key. fn is called with the current
+// value of key and whether it exists, and returns the new value and whether
+// to keep the key at all; returning false deletes it. Existing keys keep
+// their expiry while new ones are stored as with Put.
+//
+// fn runs with the cache locked so it must be quick and must not call
+// methods of the cache.
+func (p *Cache) Update(key string,
+	fn func(old interface{}, exists bool) (interface{}, bool)) error {
+	return p.modify(key, 0, false, fn)
+}
+
+// UpdateWithExpiry is like Update but stores the key, whether new or
+// existing, for duration seconds.
+func (p *Cache) UpdateWithExpiry(key string, duration int,
+	fn func(old interface{}, exists bool) (interface{}, bool)) error {
+	return p.modify(key, duration, true, fn)
+}
+
+// modify implements Update, storing the key for duration seconds if
+// setExpiry is true and otherwise keeping the expiry of existing keys
+func (p *Cache) modify(key string, duration int, setExpiry bool,
+	fn func(old interface{}, exists bool) (interface{}, bool)) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	ckey := p.canonicalKey(key)
+	var cv *CacheValue
+	var old interface{}
+	if cv = p.find(ckey); cv != nil {
+		old = p.clone(cv.Value)
+	}
+
+	value, keep := fn(old, cv != nil)
+	if !keep {
+		if cv != nil {
+			p.remove(cv, EventDelete)
+		}
+		return nil
+	}
+
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err != nil {
+		return err
+	}
+
+	if cv == nil || setExpiry {
+		if !setExpiry {
+			duration = p.defaultDuration(ckey, value)
+		}
+
+		o := entryOptions{duration: duration}
+		if cv != nil {
+			o.priority = cv.Priority
+			o.metadata = cv.Metadata
+			o.deps = cv.deps
+		}
+
+		_, _, err = p.put(key, value, o)
+		return err
+	}
+
+	if value, err = p.encode(value); err != nil {
+		return err
+	}
+
+	p.setValue(cv, value)
+	cv.Version = p.nextVersion()
+	p.publish(p.event(EventUpdate, cv))
+	return nil
+}
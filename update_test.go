@@ -0,0 +1,72 @@
+package expiringcache
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdate(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cache.Update("a", func(old interface{}, exists bool) (interface{}, bool) {
+				if !exists {
+					return []int{i}, true
+				}
+				return append(old.([]int), i), true
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(cache.Get("a").([]int)); n != 100 {
+		t.Errorf("Got %d appended values, expected 100", n)
+	}
+
+	cache.Update("a", func(old interface{}, exists bool) (interface{}, bool) {
+		return nil, false
+	})
+
+	if cache.Exists("a") {
+		t.Errorf("Update did not delete key")
+	}
+}
+
+func TestUpdateWithExpiry(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	cache.UpdateWithExpiry("a", 5, func(old interface{}, exists bool) (interface{}, bool) {
+		return old.(int) + 1, true
+	})
+
+	e, _ := cache.GetEntry("a")
+	if e.Value.(int) != 2 || e.ExpireAt-e.CreatedAt > 6 {
+		t.Errorf("UpdateWithExpiry did not set value and expiry")
+	}
+}
+
+func TestUpdateCanonicalKey(t *testing.T) {
+	var got string
+	cache := Cache{KeyTransform: strings.ToLower,
+		TTLFunc: func(key string, value interface{}) time.Duration {
+			got = key
+			return time.Minute
+		}}
+	cache.Init()
+
+	cache.Update("A", func(old interface{}, exists bool) (interface{}, bool) {
+		return 1, true
+	})
+	if got != "a" {
+		t.Errorf("TTLFunc called with %q, expected the canonical key", got)
+	}
+}
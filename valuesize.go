@@ -0,0 +1,51 @@
+package expiringcache
+
+import "unicode/utf8"
+
+// OversizePolicy decides what happens to values larger than MaxValueBytes.
+// Truncated strings are cut before the first character that doesn't fit
+// whole, and truncated byte slices are copied so the caller's array isn't
+// shared with the cache.
+type OversizePolicy int
+
+const (
+	RejectOversize   OversizePolicy = iota // Set returns ErrTooLarge
+	TruncateOversize                       // value is cut to MaxValueBytes
+)
+
+// limitValue applies policy to value if it is a []byte or string longer
+// than max bytes
+func limitValue(value interface{}, max int, policy OversizePolicy) (interface{}, error) {
+	if max <= 0 {
+		return value, nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		if len(v) <= max {
+			return v, nil
+		}
+
+		if policy == TruncateOversize {
+			return append([]byte(nil), v[:max]...), nil
+		}
+
+	case string:
+		if len(v) <= max {
+			return v, nil
+		}
+
+		if policy == TruncateOversize {
+			n := max
+			for n > 0 && !utf8.RuneStart(v[n]) {
+				n--
+			}
+			return v[:n], nil
+		}
+
+	default:
+		return value, nil
+	}
+
+	return nil, ErrTooLarge
+}
@@ -0,0 +1,54 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestMaxValueBytes(t *testing.T) {
+	cache := Cache{Duration: 60, MaxValueBytes: 3}
+	cache.Init()
+
+	if err := cache.Set("a", "abcd"); err != ErrTooLarge {
+		t.Errorf("Oversized value accepted, err %v", err)
+	}
+
+	if cache.Exists("a") {
+		t.Errorf("Rejected value was stored")
+	}
+
+	if err := cache.Set("b", "abc"); err != nil {
+		t.Errorf("Value within limit rejected, err %v", err)
+	}
+
+	cache.OversizePolicy = TruncateOversize
+	cache.Put("c", []byte("abcd"))
+	if string(cache.Get("c").([]byte)) != "abc" {
+		t.Errorf("Oversized value not truncated")
+	}
+
+	bcache := BytesCache{Duration: 60, ArenaSize: 1024, MaxValueBytes: 3}
+	bcache.Init()
+
+	if err := bcache.Put("a", []byte("abcd")); err != ErrTooLarge {
+		t.Errorf("Oversized value accepted by BytesCache, err %v", err)
+	}
+}
+
+func TestTruncateOversize(t *testing.T) {
+	cache := Cache{Duration: 60, MaxValueBytes: 4,
+		OversizePolicy: TruncateOversize}
+	cache.Init()
+
+	// é is 2 bytes, only half of the second one fits
+	cache.Put("s", "aéé")
+	if v := cache.Get("s"); v != "aé" {
+		t.Errorf("String truncated to %q", v)
+	}
+
+	b := []byte("abcdef")
+	cache.Put("b", b)
+	b[0] = 'x'
+	if v := cache.Get("b").([]byte); string(v) != "abcd" {
+		t.Errorf("Truncated bytes share the caller's array: %q", v)
+	}
+}
@@ -0,0 +1,75 @@
+package expiringcache
+
+// nextVersion must be called with the lock held. Versions are unique across
+// the whole cache so a key that is deleted and added again never reuses one.
+func (p *Cache) nextVersion() uint64 {
+	p.version++
+	return p.version
+}
+
+// GetWithVersion returns the value of key along with its version, which
+// can later be passed to CompareAndSwap. The version is 0 if key is missing.
+func (p *Cache) GetWithVersion(key string) (interface{}, uint64) {
+	p.Lock()
+	defer p.Unlock()
+
+	cv := p.access(key)
+	if cv == nil {
+		return nil, 0
+	}
+
+	return p.clone(cv.Value), cv.Version
+}
+
+// GetIfChanged is like GetWithVersion but only returns the value if the
+// key's version is no longer version, e.g. one returned by an earlier
+// call, so polling callers don't copy or decode values they already have.
+// It reports whether the key changed, which includes being removed; the
+// new version is then 0.
+func (p *Cache) GetIfChanged(key string, version uint64) (interface{},
+	uint64, bool) {
+	p.Lock()
+	defer p.Unlock()
+
+	cv := p.access(key)
+	if cv == nil {
+		return nil, 0, version != 0
+	}
+
+	if cv.Version == version {
+		return nil, version, false
+	}
+
+	return p.clone(cv.Value), cv.Version, true
+}
+
+// CompareAndSwap replaces the value of key with value, keeping its expiry,
+// if the key's version is still expectedVersion. It reports whether the
+// value was replaced.
+func (p *Cache) CompareAndSwap(key string, expectedVersion uint64,
+	value interface{}) bool {
+	value, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+	if err == nil {
+		value, err = p.encode(value)
+	}
+	if err != nil {
+		return false
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	if p.closed {
+		return false
+	}
+
+	cv := p.find(p.canonicalKey(key))
+	if cv == nil || cv.Version != expectedVersion {
+		return false
+	}
+
+	p.setValue(cv, value)
+	cv.Version = p.nextVersion()
+	p.publish(p.event(EventUpdate, cv))
+	return true
+}
@@ -0,0 +1,71 @@
+package expiringcache
+
+import (
+	"testing"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	v, version := cache.GetWithVersion("a")
+	if v.(int) != 1 || version == 0 {
+		t.Fatalf("GetWithVersion returned %v, %d", v, version)
+	}
+
+	if !cache.CompareAndSwap("a", version, 2) {
+		t.Errorf("CompareAndSwap failed with current version")
+	}
+
+	if cache.CompareAndSwap("a", version, 3) {
+		t.Errorf("CompareAndSwap succeeded with stale version")
+	}
+
+	if cache.Get("a").(int) != 2 {
+		t.Errorf("Value lost by concurrent swap")
+	}
+
+	cache.Del("a")
+	cache.Put("a", 4)
+	if _, v := cache.GetWithVersion("a"); v == version {
+		t.Errorf("Version reused after key was added again")
+	}
+
+	if _, v := cache.GetWithVersion("b"); v != 0 {
+		t.Errorf("Missing key has version %d", v)
+	}
+}
+
+func TestGetIfChanged(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	cache.Put("a", 1)
+	v, version, changed := cache.GetIfChanged("a", 0)
+	if v != 1 || version == 0 || !changed {
+		t.Errorf("GetIfChanged returned %v, %d, %v", v, version, changed)
+	}
+
+	if v, next, changed := cache.GetIfChanged("a", version); v != nil ||
+		next != version || changed {
+		t.Errorf("GetIfChanged of unchanged key returned %v, %d, %v", v, next,
+			changed)
+	}
+
+	cache.Put("a", 2)
+	if v, next, changed := cache.GetIfChanged("a", version); v != 2 ||
+		next == version || !changed {
+		t.Errorf("GetIfChanged of updated key returned %v, %d, %v", v, next,
+			changed)
+	}
+
+	cache.Del("a")
+	if _, next, changed := cache.GetIfChanged("a", version); next != 0 ||
+		!changed {
+		t.Errorf("GetIfChanged of removed key returned %d, %v", next, changed)
+	}
+	if _, _, changed := cache.GetIfChanged("a", 0); changed {
+		t.Errorf("GetIfChanged of missing key reported a change")
+	}
+}
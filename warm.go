@@ -0,0 +1,52 @@
+package expiringcache
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Warm adds all entries to the cache with the given ttl in a single pass,
+// e.g. to prime the cache at startup. If any value is rejected because of
+// MaxValueBytes, nothing is added. With RejectOnFull, adding stops with
+// ErrCacheFull once the cache is full.
+func (p *Cache) Warm(entries map[string]interface{}, ttl time.Duration) error {
+	values := make(map[string]interface{}, len(entries))
+	for key, value := range entries {
+		v, err := limitValue(value, p.MaxValueBytes, p.OversizePolicy)
+		if err != nil {
+			return err
+		}
+		values[key] = v
+	}
+
+	duration := seconds(ttl)
+
+	p.Lock()
+	defer p.Unlock()
+
+	for key, value := range values {
+		_, _, err := p.put(key, value, entryOptions{duration: duration})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WarmFromJSON reads a JSON object from r and adds each of its members to
+// the cache for Duration seconds. Values are decoded as by encoding/json
+// into an interface{}, so numbers become float64.
+func (p *Cache) WarmFromJSON(r io.Reader) error {
+	var entries map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	p.Lock()
+	ttl := time.Duration(p.Duration) * time.Second
+	p.Unlock()
+
+	return p.Warm(entries, ttl)
+}
@@ -0,0 +1,49 @@
+package expiringcache
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWarm(t *testing.T) {
+	cache := Cache{Duration: 60}
+	cache.Init()
+
+	err := cache.Warm(map[string]interface{}{"a": 1, "b": 2}, time.Minute)
+	if err != nil || cache.Count() != 2 || cache.Get("b").(int) != 2 {
+		t.Errorf("Warm failed to add entries, err %v", err)
+	}
+
+	err = cache.WarmFromJSON(strings.NewReader(`{"c": "x", "d": [1, 2]}`))
+	if err != nil || cache.Count() != 4 || cache.Get("c").(string) != "x" {
+		t.Errorf("WarmFromJSON failed to add entries, err %v", err)
+	}
+
+	if cache.WarmFromJSON(strings.NewReader(`[1, 2]`)) == nil {
+		t.Errorf("WarmFromJSON accepted malformed input")
+	}
+
+	// sub-second TTLs round up rather than to 0, which never expires
+	cache.Warm(map[string]interface{}{"e": 1}, 500*time.Millisecond)
+	if ttl, _ := cache.TTL("e"); ttl == NoExpiry || ttl > time.Second {
+		t.Errorf("Sub-second TTL stored as %v", ttl)
+	}
+}
+
+func FuzzWarmFromJSON(f *testing.F) {
+	f.Add(`{"a": 1, "b": "x"}`)
+	f.Add(`{"a": {"b": [1, 2]}}`)
+	f.Add(`[1, 2]`)
+
+	f.Fuzz(func(t *testing.T, data string) {
+		cache := Cache{Duration: 60, MaxValueBytes: 8}
+		cache.Init()
+
+		if err := cache.WarmFromJSON(strings.NewReader(data)); err != nil {
+			if cache.Count() != 0 {
+				t.Errorf("Keys added from invalid input %q", data)
+			}
+		}
+	})
+}
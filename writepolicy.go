@@ -0,0 +1,87 @@
+package expiringcache
+
+import (
+	"time"
+)
+
+// WritePolicy decides what happens to new keys when the cache is full
+type WritePolicy int
+
+const (
+	EvictOnFull  WritePolicy = iota // existing keys are evicted to make room
+	DropOnFull                      // the new key is silently discarded
+	RejectOnFull                    // Set returns ErrCacheFull
+)
+
+// SaturatedPolicy decides what happens to new keys when the cache is full
+// and no key can be evicted, as all are protected by ProtectedPriority
+type SaturatedPolicy int
+
+const (
+	RejectWhenSaturated SaturatedPolicy = iota // the write returns ErrCacheFull
+	EvictWhenSaturated                         // protected keys are evicted anyway
+	BlockWhenSaturated                         // Put waits for room
+)
+
+// protected reports whether cv may not be evicted to make room
+func (p *Cache) protected(cv *CacheValue) bool {
+	return p.ProtectedPriority > 0 && cv.Priority >= p.ProtectedPriority
+}
+
+// saturated handles a new key costing c for which no room could be made as
+// set by Saturated. It must be called with the lock held.
+func (p *Cache) saturated(c int64) error {
+	if p.Saturated != EvictWhenSaturated {
+		return ErrCacheFull
+	}
+
+	for (p.full() || p.overCost(c)) && p.data.Len() > 0 {
+		p.remove(p.lowest(), EventEvict)
+		p.evicted++
+	}
+	return nil
+}
+
+// evictable reports whether any key may be evicted to make room. Unlike
+// victim it doesn't sample keys, so it draws nothing from RandSource.
+func (p *Cache) evictable() bool {
+	for i := 0; i < p.data.Len(); i++ {
+		if !p.protected(p.data.At(i).(*CacheValue)) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForRoom waits, with BlockWhenSaturated, while storing key would need
+// to evict a protected key, returning ErrCacheFull once SaturatedTimeout
+// has passed. It must be called with the lock held, which it releases
+// while waiting.
+func (p *Cache) waitForRoom(key string) error {
+	if p.room == nil {
+		return nil
+	}
+
+	timedOut := false
+	var timer *time.Timer
+	for p.full() && !p.evictable() && p.find(p.canonicalKey(key)) == nil {
+		switch {
+		case p.closed:
+			return ErrClosed
+		case timedOut:
+			return ErrCacheFull
+		case timer == nil && p.SaturatedTimeout > 0:
+			timer = time.AfterFunc(p.SaturatedTimeout, func() {
+				p.Lock()
+				timedOut = true
+				p.room.Broadcast()
+				p.Unlock()
+			})
+			defer timer.Stop()
+		}
+
+		p.room.Wait()
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package expiringcache
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePolicy(t *testing.T) {
+	drop := Cache{Duration: 60, Max: 1, NEvictions: 1, WritePolicy: DropOnFull}
+	drop.Init()
+
+	drop.Put("a", 1)
+	if err := drop.Set("b", 2); err != nil || drop.Exists("b") ||
+		!drop.Exists("a") {
+		t.Errorf("DropOnFull did not drop the new key: %v", err)
+	}
+
+	reject := Cache{Duration: 60, Max: 1, NEvictions: 1,
+		WritePolicy: RejectOnFull}
+	reject.Init()
+
+	reject.Put("a", 1)
+	if err := reject.Set("b", 2); err != ErrCacheFull || reject.Exists("b") {
+		t.Errorf("RejectOnFull returned %v", err)
+	}
+
+	// updates are allowed when full
+	if err := reject.Set("a", 3); err != nil || reject.Get("a") != 3 {
+		t.Errorf("Update of existing key rejected: %v", err)
+	}
+}
+
+func TestSaturated(t *testing.T) {
+	newCache := func(policy SaturatedPolicy, timeout time.Duration) *Cache {
+		cache := &Cache{Duration: 60, Max: 2, NEvictions: 1,
+			ProtectedPriority: 10, Saturated: policy,
+			SaturatedTimeout: timeout}
+		cache.Init()
+		cache.PutWithPriority("a", 1, 60, 10)
+		cache.PutWithPriority("b", 2, 60, 20)
+		return cache
+	}
+
+	reject := newCache(RejectWhenSaturated, 0)
+	if err := reject.Set("c", 3); err != ErrCacheFull || reject.Count() != 2 {
+		t.Errorf("RejectWhenSaturated returned %v", err)
+	}
+	reject.PutWithPriority("a", 1, 60, 0)
+	if err := reject.Set("c", 3); err != nil || reject.Exists("a") {
+		t.Errorf("Unprotected key not evicted: %v", err)
+	}
+
+	evict := newCache(EvictWhenSaturated, 0)
+	if err := evict.Set("c", 3); err != nil || evict.Exists("a") ||
+		!evict.Exists("b") {
+		t.Errorf("EvictWhenSaturated did not evict the lowest priority key: %v",
+			err)
+	}
+
+	timeout := newCache(BlockWhenSaturated, 10*time.Millisecond)
+	if err := timeout.Set("c", 3); err != ErrCacheFull {
+		t.Errorf("BlockWhenSaturated did not time out: %v", err)
+	}
+
+	block := newCache(BlockWhenSaturated, 0)
+	go func() {
+		time.Sleep(time.Millisecond)
+		block.Del("a")
+	}()
+	if err := block.Set("c", 3); err != nil || !block.Exists("c") {
+		t.Errorf("BlockWhenSaturated did not wait for room: %v", err)
+	}
+}
+
+func TestSaturatedRandSource(t *testing.T) {
+	evicted := func(saturated SaturatedPolicy) string {
+		cache := Cache{Duration: 60, Max: 10, NEvictions: 1,
+			ProtectedPriority: 100, Saturated: saturated,
+			RandSource: rand.NewSource(1)}
+		cache.Init()
+
+		var keys []string
+		events, cancel := cache.Subscribe()
+		defer cancel()
+
+		for i := 0; i < 20; i++ {
+			cache.Put(strconv.Itoa(i), i)
+		}
+
+		for len(events) > 0 {
+			if e := <-events; e.Type == EventEvict {
+				keys = append(keys, e.Key)
+			}
+		}
+		return strings.Join(keys, ",")
+	}
+
+	// waiting for room must not change which keys are sampled
+	if a, b := evicted(RejectWhenSaturated),
+		evicted(BlockWhenSaturated); a != b {
+		t.Errorf("Evicted %s when blocking, %s otherwise", b, a)
+	}
+}